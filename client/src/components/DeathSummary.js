@@ -0,0 +1,83 @@
+// 死亡信息面板：显示击杀者、长度和存活时间，冷却结束后可以复活
+export class DeathSummary {
+    constructor(death, cooldown, onRespawn) {
+        this.onRespawn = onRespawn;
+        this.createElements(death);
+        this.startCountdown(cooldown);
+    }
+
+    createElements(death) {
+        this.container = document.createElement('div');
+        this.container.id = 'deathSummary';
+        Object.assign(this.container.style, {
+            position: 'fixed',
+            top: '20px',
+            left: '50%',
+            transform: 'translateX(-50%)',
+            display: 'flex',
+            flexDirection: 'column',
+            alignItems: 'center',
+            gap: '8px',
+            padding: '12px 20px',
+            background: 'rgba(0, 0, 0, 0.7)',
+            color: 'white',
+            borderRadius: '5px',
+            fontSize: '16px',
+            zIndex: '1000'
+        });
+
+        const title = document.createElement('div');
+        title.style.fontSize = '18px';
+        title.textContent = death.killerName ? `你被 ${death.killerName} 击杀了` : '游戏结束！';
+
+        const detail = document.createElement('div');
+        detail.textContent = `得分：${death.score}　击杀：${death.kills}　长度：${death.length}　存活：${Math.round(death.alive)} 秒`;
+
+        this.button = document.createElement('button');
+        Object.assign(this.button.style, {
+            padding: '10px 20px',
+            fontSize: '16px',
+            backgroundColor: '#4CAF50',
+            color: 'white',
+            border: 'none',
+            borderRadius: '5px',
+            cursor: 'pointer'
+        });
+        this.button.onclick = () => {
+            this.button.disabled = true;
+            this.onRespawn();
+        };
+
+        this.container.append(title, detail, this.button);
+        document.body.appendChild(this.container);
+    }
+
+    // 冷却期间按钮不可用并显示剩余秒数
+    startCountdown(cooldown) {
+        const readyAt = Date.now() + cooldown;
+        const update = () => {
+            const remaining = Math.ceil((readyAt - Date.now()) / 1000);
+            if (remaining > 0) {
+                this.button.disabled = true;
+                this.button.textContent = `${remaining} 秒后可以复活`;
+                return;
+            }
+            clearInterval(this.timer);
+            this.button.disabled = false;
+            this.button.textContent = '复活';
+        };
+        update();
+        this.timer = setInterval(update, 200);
+    }
+
+    // 复活请求被拒绝时允许再次尝试
+    showError(message) {
+        this.button.disabled = false;
+        this.button.textContent = message;
+    }
+
+    remove() {
+        clearInterval(this.timer);
+        this.container.remove();
+    }
+}
@@ -0,0 +1,71 @@
+// 加入游戏的表单，填写昵称并选择蛇的颜色
+export class JoinForm {
+    constructor(onSubmit, defaults = {}) {
+        this.onSubmit = onSubmit;
+        this.options = null;
+        this.createElements(defaults);
+    }
+
+    createElements(defaults) {
+        this.container = document.createElement('form');
+        this.container.id = 'joinForm';
+        Object.assign(this.container.style, {
+            position: 'fixed',
+            top: '50%',
+            left: '50%',
+            transform: 'translate(-50%, -50%)',
+            display: 'flex',
+            flexDirection: 'column',
+            gap: '10px',
+            padding: '20px',
+            background: 'rgba(0, 0, 0, 0.7)',
+            color: 'white',
+            borderRadius: '5px',
+            fontSize: '16px',
+            zIndex: '1000'
+        });
+
+        // 昵称，留空时由服务器随机生成
+        this.nameInput = document.createElement('input');
+        this.nameInput.placeholder = '昵称（留空随机生成）';
+        this.nameInput.maxLength = 16;
+        this.nameInput.value = defaults.name || '';
+
+        // 颜色
+        const colorLabel = document.createElement('label');
+        colorLabel.textContent = '颜色 ';
+        this.colorInput = document.createElement('input');
+        this.colorInput.type = 'color';
+        this.colorInput.value = defaults.color || '#4CAF50';
+        colorLabel.appendChild(this.colorInput);
+
+        this.error = document.createElement('div');
+        this.error.style.color = '#FF6B6B';
+        this.error.style.fontSize = '14px';
+
+        this.button = document.createElement('button');
+        this.button.type = 'submit';
+        this.button.textContent = '开始游戏';
+
+        this.container.onsubmit = (event) => {
+            event.preventDefault();
+            this.error.textContent = '';
+            this.button.disabled = true;
+            this.options = { name: this.nameInput.value.trim(), color: this.colorInput.value };
+            this.onSubmit(this.options);
+        };
+
+        this.container.append(this.nameInput, colorLabel, this.error, this.button);
+        document.body.appendChild(this.container);
+        this.nameInput.focus();
+    }
+
+    showError(message) {
+        this.error.textContent = message;
+        this.button.disabled = false;
+    }
+
+    remove() {
+        this.container.remove();
+    }
+}
@@ -0,0 +1,36 @@
+// 左上角的实时排行榜，高亮玩家自己的蛇
+export class Leaderboard {
+    constructor() {
+        this.container = document.createElement('div');
+        this.container.id = 'leaderboard';
+        Object.assign(this.container.style, {
+            position: 'fixed',
+            top: '10px',
+            left: '10px',
+            minWidth: '160px',
+            padding: '6px 10px',
+            background: 'rgba(0, 0, 0, 0.5)',
+            color: 'white',
+            borderRadius: '5px',
+            fontSize: '13px',
+            zIndex: '900'
+        });
+        document.body.appendChild(this.container);
+    }
+
+    update(leaderboard, playerId) {
+        this.container.innerHTML = '';
+        const title = document.createElement('div');
+        title.style.fontWeight = 'bold';
+        title.textContent = '排行榜';
+        this.container.appendChild(title);
+
+        leaderboard.entries.forEach((entry, i) => {
+            const row = document.createElement('div');
+            row.style.color = entry.id === playerId ? '#FFD700' : entry.color;
+            const tag = entry.isAI ? ' (AI)' : '';
+            row.textContent = `${i + 1}. ${entry.name}${tag}  ${entry.score}分 ${entry.kills}杀`;
+            this.container.appendChild(row);
+        });
+    }
+}
@@ -0,0 +1,82 @@
+// 回放控制条，提供暂停、跳转和倍速功能
+export class ReplayControls {
+    constructor(onControl) {
+        this.onControl = onControl;
+        this.seeking = false;
+        this.createElements();
+    }
+
+    createElements() {
+        this.container = document.createElement('div');
+        this.container.id = 'replayControls';
+        Object.assign(this.container.style, {
+            position: 'fixed',
+            bottom: '10px',
+            left: '50%',
+            transform: 'translateX(-50%)',
+            display: 'flex',
+            alignItems: 'center',
+            gap: '10px',
+            padding: '6px 12px',
+            background: 'rgba(0, 0, 0, 0.6)',
+            color: 'white',
+            borderRadius: '5px',
+            fontSize: '14px',
+            zIndex: '1000'
+        });
+
+        // 播放/暂停按钮
+        this.playButton = document.createElement('button');
+        this.playButton.onclick = () => {
+            this.onControl({ action: this.paused ? 'play' : 'pause' });
+        };
+
+        // 进度条，拖动结束后跳转
+        this.slider = document.createElement('input');
+        this.slider.type = 'range';
+        this.slider.style.width = '40vw';
+        this.slider.oninput = () => {
+            this.seeking = true;
+            this.label.textContent = this.formatTick(Number(this.slider.value));
+        };
+        this.slider.onchange = () => {
+            this.seeking = false;
+            this.onControl({ action: 'seek', tick: Number(this.slider.value) });
+        };
+
+        this.label = document.createElement('span');
+
+        // 倍速选择
+        this.speedSelect = document.createElement('select');
+        [0.25, 0.5, 1, 2, 4, 8].forEach(speed => {
+            const option = document.createElement('option');
+            option.value = speed;
+            option.textContent = `${speed}x`;
+            this.speedSelect.appendChild(option);
+        });
+        this.speedSelect.onchange = () => {
+            this.onControl({ action: 'speed', speed: Number(this.speedSelect.value) });
+        };
+
+        this.container.append(this.playButton, this.slider, this.label, this.speedSelect);
+        document.body.appendChild(this.container);
+    }
+
+    formatTick(tick) {
+        return `${tick - this.start} / ${this.end - this.start}`;
+    }
+
+    update(info) {
+        this.paused = info.paused;
+        this.start = info.start;
+        this.end = info.end;
+        this.playButton.textContent = info.paused ? '播放' : '暂停';
+        this.slider.min = info.start;
+        this.slider.max = info.end;
+        this.speedSelect.value = info.speed;
+        if (!this.seeking) {
+            this.slider.value = info.tick;
+            this.label.textContent = this.formatTick(info.tick);
+        }
+    }
+}
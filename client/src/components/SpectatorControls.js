@@ -0,0 +1,100 @@
+// 观战控制条：选择跟随的玩家，或切换为自由视角后用方向键移动视野
+export class SpectatorControls {
+    constructor(onSpectate) {
+        this.onSpectate = onSpectate;
+        this.follow = '';
+        this.center = null;
+        this.options = '';
+        this.createElements();
+        this.handleKeyPress = this.handleKeyPress.bind(this);
+        document.addEventListener('keydown', this.handleKeyPress);
+    }
+
+    createElements() {
+        this.container = document.createElement('div');
+        this.container.id = 'spectatorControls';
+        Object.assign(this.container.style, {
+            position: 'fixed',
+            bottom: '10px',
+            left: '50%',
+            transform: 'translateX(-50%)',
+            display: 'flex',
+            alignItems: 'center',
+            gap: '10px',
+            padding: '6px 12px',
+            background: 'rgba(0, 0, 0, 0.6)',
+            color: 'white',
+            borderRadius: '5px',
+            fontSize: '14px',
+            zIndex: '1000'
+        });
+
+        const label = document.createElement('span');
+        label.textContent = '观战';
+
+        // 跟随的玩家，第一项为自由视角
+        this.followSelect = document.createElement('select');
+        this.followSelect.onchange = () => {
+            this.follow = this.followSelect.value;
+            if (this.follow) {
+                this.onSpectate({ follow: this.follow });
+            }
+        };
+
+        const hint = document.createElement('span');
+        hint.textContent = '自由视角下用方向键移动';
+
+        this.container.append(label, this.followSelect, hint);
+        document.body.appendChild(this.container);
+    }
+
+    // 根据视野内的蛇更新可跟随的玩家列表，保留当前的选择
+    update(snakes, follow, view) {
+        if (follow !== undefined) {
+            this.follow = follow;
+        }
+        if (view) {
+            this.center = {
+                x: Math.floor((view.minX + view.maxX + 1) / 2),
+                y: Math.floor((view.minY + view.maxY + 1) / 2)
+            };
+        }
+        const players = snakes.filter(snake => !snake.isAI && !snake.dead);
+        // 玩家列表没有变化时不重建选项，避免打开的下拉框被关闭
+        const options = players.map(snake => `${snake.id}:${snake.name}`).join(',') + `|${this.follow}`;
+        if (options === this.options) {
+            return;
+        }
+        this.options = options;
+        this.followSelect.innerHTML = '';
+        this.followSelect.appendChild(new Option('自由视角', ''));
+        players.forEach(snake => this.followSelect.appendChild(new Option(snake.name, snake.id)));
+        if (this.follow && !players.some(snake => snake.id === this.follow)) {
+            this.followSelect.appendChild(new Option(this.follow, this.follow));
+        }
+        this.followSelect.value = this.follow;
+    }
+
+    handleKeyPress(event) {
+        const steps = {
+            ArrowUp: { x: 0, y: -1 },
+            ArrowDown: { x: 0, y: 1 },
+            ArrowLeft: { x: -1, y: 0 },
+            ArrowRight: { x: 1, y: 0 }
+        };
+        const step = steps[event.key];
+        if (!step || !this.center) {
+            return;
+        }
+        // 移动视野时停止跟随
+        this.follow = '';
+        this.followSelect.value = '';
+        this.center = { x: this.center.x + step.x * 5, y: this.center.y + step.y * 5 };
+        this.onSpectate({ camera: this.center });
+    }
+
+    remove() {
+        document.removeEventListener('keydown', this.handleKeyPress);
+        this.container.remove();
+    }
+}
@@ -1,15 +1,36 @@
 import { Renderer } from './Renderer.js';
 import { Controller } from './Controller.js';
+import { ReplayControls } from '../components/ReplayControls.js';
+import { JoinForm } from '../components/JoinForm.js';
+import { SpectatorControls } from '../components/SpectatorControls.js';
+import { DeathSummary } from '../components/DeathSummary.js';
+import { Leaderboard } from '../components/Leaderboard.js';
+import { WebSocketClient } from '../network/WebSocketClient.js';
 
 export class Game {
     constructor() {
         this.canvas = document.getElementById('gameCanvas');
         this.renderer = new Renderer(this.canvas);
         this.player = null;
-        this.ws = null;
+        this.playerId = null;
+        this.session = null;
+        this.joinOptions = null;
+        this.joinForm = null;
+        this.deathSummary = null;
+        this.leaderboard = null;
+        this.config = null;
+        this.client = null;
         this.snakes = new Map();
         this.apples = [];
+        this.minimap = [];
+        this.view = null;
         this.controller = null;
+        this.replayControls = null;
+        // 页面地址带 mode=spectate 时以观众身份连接，不创建蛇
+        this.spectating = new URLSearchParams(window.location.search).get('mode') === 'spectate';
+        this.spectatorControls = null;
+        this.seq = 0;
+        this.awaitingFull = false;
 
         // 初始化canvas尺寸
         this.initCanvasSize();
@@ -40,120 +61,236 @@ export class Game {
     }
 
     connectWebSocket() {
+        this.seq = 0;
+        this.awaitingFull = false;
+
+        this.client = new WebSocketClient(() => this.socketUrl());
+        this.client.onOpen = () => {
+            if (!this.spectating) {
+                this.join();
+            }
+        };
+        this.client.on('welcome', (welcome) => {
+            this.renderer.setMap(welcome.map);
+            if (welcome.spectatorId) {
+                if (!this.spectatorControls) {
+                    this.spectatorControls = new SpectatorControls((spectate) => this.client.send('spectate', spectate));
+                }
+                this.spectatorControls.update([], welcome.follow || '');
+                return;
+            }
+            this.config = welcome.config;
+            this.session = welcome.token;
+            this.playerId = welcome.snakeId;
+            // 复活后会收到新的欢迎消息
+            if (this.deathSummary) {
+                this.deathSummary.remove();
+                this.deathSummary = null;
+            }
+            if (this.joinForm) {
+                // 加入成功后才记住表单中的选择
+                this.joinOptions = this.joinForm.options;
+                localStorage.setItem('snakesol.player', JSON.stringify(this.joinOptions));
+                this.joinForm.remove();
+                this.joinForm = null;
+            }
+        });
+        this.client.on('state', (state) => this.updateGameState(state));
+        this.client.on('delta', (delta) => this.applyDelta(delta));
+        this.client.on('death', (death) => {
+            if (death.snakeId === this.playerId) {
+                this.showDeath(death);
+            }
+        });
+        this.client.on('leaderboard', (leaderboard) => {
+            if (!this.leaderboard) {
+                this.leaderboard = new Leaderboard();
+            }
+            this.leaderboard.update(leaderboard, this.playerId);
+        });
+        this.client.on('kill', (kill) => {
+            console.log(`${kill.killerName} 击杀了 ${kill.victimName}`);
+        });
+        this.client.on('error', (error) => {
+            console.warn(`服务器错误 ${error.code}: ${error.message}`);
+            if (this.joinForm) {
+                this.joinForm.showError(error.message);
+            }
+            if (error.code === 'cannot_respawn' && this.deathSummary) {
+                this.deathSummary.showError(error.message);
+            }
+        });
+        this.client.connect().catch(() => {});
+    }
+
+    // 连接建立后发送join消息：第一次加入时先显示表单，之后重连和重新开始时沿用上次的选择
+    join() {
+        if (this.joinOptions) {
+            this.client.send('join', { ...this.joinOptions, session: this.session });
+            return;
+        }
+        if (this.joinForm) {
+            return;
+        }
+        const saved = JSON.parse(localStorage.getItem('snakesol.player') || '{}');
+        this.joinForm = new JoinForm((options) => this.client.send('join', options), saved);
+    }
+
+    socketUrl() {
         const protocol = window.location.protocol === 'https:' ? 'wss://' : 'ws://';
         const hostname = window.location.hostname;
         const port = window.location.port ? `:${window.location.port}` : ''; // 如果端口不是默认的 80 或 443，则需要包含
          
-        // 构建 WebSocket URL
-        const socketUrl = `${protocol}${hostname}${port}/ws`; 
-
-        this.ws = new WebSocket(socketUrl);
+        // 构建 WebSocket URL，页面地址中的 room 参数决定加入的房间，使用增量协议同步状态
+        const params = new URLSearchParams({ protocol: 'delta' });
+        const query = new URLSearchParams(window.location.search);
+        const room = query.get('room');
+        if (room) {
+            params.set('room', room);
+        }
+        if (this.spectating) {
+            params.set('mode', 'spectate');
+            if (query.get('follow')) {
+                params.set('follow', query.get('follow'));
+            }
+        }
+        return `${protocol}${hostname}${port}/ws?${params}`;
+    }
 
-        this.ws.onopen = () => {
-            console.log('已连接到服务器');
-        };
+    // 应用增量状态，发现序号不连续时请求服务端重新发送完整快照
+    applyDelta(delta) {
+        if (this.awaitingFull) {
+            return;
+        }
+        if (delta.seq !== this.seq + 1) {
+            console.warn(`状态序号不连续: 期望 ${this.seq + 1}，收到 ${delta.seq}`);
+            this.awaitingFull = true;
+            this.client.send('resync');
+            return;
+        }
+        this.seq = delta.seq;
+        this.updateView(delta);
 
-        this.ws.onmessage = (event) => {
-            const gameState = JSON.parse(event.data);
-            this.updateGameState(gameState);
-        };
+        (delta.spawned || []).forEach(snake => this.snakes.set(snake.id, snake));
+        (delta.moved || []).forEach(move => {
+            const snake = this.snakes.get(move.id);
+            if (!snake) {
+                return;
+            }
+            // 旧的蛇头成为身体第一节，然后截断到新的长度
+            snake.body.unshift({ x: snake.x, y: snake.y });
+            snake.body.length = move.length;
+            snake.x = move.x;
+            snake.y = move.y;
+            snake.direction = move.direction;
+        });
+        (delta.removed || []).forEach(id => this.snakes.delete(id));
+        (delta.died || []).forEach(id => this.snakes.delete(id));
+        this.updatePlayer();
 
-        this.ws.onclose = () => {
-            console.log('与服务器断开连接');
-            setTimeout(() => this.init(), 1000);
-        };
+        const apples = new Map(this.apples.map(apple => [`${apple.x},${apple.y}`, apple]));
+        (delta.applesAdded || []).forEach(apple => apples.set(`${apple.x},${apple.y}`, apple));
+        (delta.applesRemoved || []).forEach(apple => apples.delete(`${apple.x},${apple.y}`));
+        this.apples = Array.from(apples.values());
 
-        this.ws.onerror = (error) => {
-            console.error('WebSocket错误:', error);
-        };
+        this.draw();
     }
 
     updateGameState(state) {
         console.log(state)
+        if (state.seq) {
+            this.seq = state.seq;
+            this.awaitingFull = false;
+        }
+
         // 如果收到场景尺寸信息，更新渲染器配置
         if (state.config && state.config.cols && state.config.rows) {
-            this.renderer.setGameSize(state.config.cols, state.config.rows);
+            this.renderer.setGameSize(state.config.cols, state.config.rows, state.config.boundary);
+        }
+
+        // 回放模式下显示控制条，不处理玩家操作；地图只在第一条回放状态中下发
+        if (state.replay) {
+            if (state.map) {
+                this.renderer.setMap(state.map);
+            }
+            if (!this.replayControls) {
+                this.replayControls = new ReplayControls((control) => {
+                    this.client.send('replay', control);
+                });
+            }
+            this.replayControls.update(state.replay);
+            // 回放不需要加入游戏
+            if (this.joinForm) {
+                this.joinForm.remove();
+                this.joinForm = null;
+            }
         }
 
         // 更新蛇的状态
         this.snakes.clear();
         for (const [id, snakeData] of Object.entries(state.snakes)) {
-            if (!snakeData.isAI && !this.player) {
-                this.player = snakeData;
-                // 创建控制器并设置方向改变回调
-                if (!this.controller) {
-                    this.controller = new Controller(this.player);
-                    this.controller.onDirectionChange = (direction) => {
-                        this.sendDirection(direction);
-                    };
-                }
-            }
             this.snakes.set(id, snakeData);
         }
+        this.updatePlayer();
 
         // 更新苹果位置
         this.apples = state.apples;
+        this.updateView(state);
 
         // 渲染游戏画面
-        this.renderer.draw(this.player, Array.from(this.snakes.values()), this.apples);
+        this.draw();
+    }
 
-        // 检查玩家是否死亡
-        if (state.deadSnakeId && this.player && state.deadSnakeId === this.player.id && !document.getElementById('restartButton')) {
-            const score = this.player.body.length;
-            // 创建重新开始按钮
-            const restartButton = document.createElement('button');
-            restartButton.id = 'restartButton';
-            restartButton.textContent = '重新开始游戏';
-            restartButton.style.position = 'fixed';
-            restartButton.style.top = '20px';
-            restartButton.style.left = '50%';
-            restartButton.style.transform = 'translateX(-50%)';
-            restartButton.style.padding = '10px 20px';
-            restartButton.style.fontSize = '16px';
-            restartButton.style.backgroundColor = '#4CAF50';
-            restartButton.style.color = 'white';
-            restartButton.style.border = 'none';
-            restartButton.style.borderRadius = '5px';
-            restartButton.style.cursor = 'pointer';
-            restartButton.style.zIndex = '1000';
-            
-            // 添加得分显示
-            const scoreDisplay = document.createElement('div');
-            scoreDisplay.id = 'scoreDisplay';
-            scoreDisplay.textContent = `游戏结束！得分：${score}`;
-            scoreDisplay.style.position = 'fixed';
-            scoreDisplay.style.top = '70px';
-            scoreDisplay.style.left = '50%';
-            scoreDisplay.style.transform = 'translateX(-50%)';
-            scoreDisplay.style.fontSize = '18px';
-            scoreDisplay.style.color = '#333';
-            scoreDisplay.style.zIndex = '1000';
-            
-            document.body.appendChild(scoreDisplay);
-            document.body.appendChild(restartButton);
-            
-            restartButton.onclick = () => {
-                this.player = null;
-                this.ws.close();
-                document.body.removeChild(restartButton);
-                document.body.removeChild(scoreDisplay);
-                this.init();
+    // 根据会话中的蛇ID找到玩家控制的蛇，并让控制器跟随最新的蛇对象
+    updatePlayer() {
+        const snake = this.playerId && this.snakes.get(this.playerId);
+        if (!snake) {
+            return;
+        }
+        this.player = snake;
+        if (!this.controller) {
+            // 创建控制器并设置方向改变回调
+            this.controller = new Controller(this.player);
+            this.controller.onDirectionChange = (direction) => {
+                this.sendDirection(direction);
             };
         }
+        this.controller.player = this.player;
     }
 
-    sendDirection(direction) {
-        if (this.ws && this.ws.readyState === WebSocket.OPEN) {
-            this.ws.send(JSON.stringify({
-                type: 'direction',
-                payload: direction
-            }));
+    // 服务端只推送视野内的状态，小地图按间隔推送，没有推送时沿用上一次的数据
+    updateView(message) {
+        this.view = message.view || null;
+        if (message.minimap) {
+            this.minimap = message.minimap;
         }
     }
 
+    draw() {
+        if (this.spectatorControls) {
+            this.spectatorControls.update(Array.from(this.snakes.values()), undefined, this.view);
+        }
+        this.renderer.draw(this.player, Array.from(this.snakes.values()), this.apples);
+        this.renderer.drawMinimap(this.minimap, this.view);
+    }
+
+    // 玩家死亡后留在原地观战，冷却结束后可以发送respawn获得一条新蛇
+    showDeath(death) {
+        if (this.deathSummary) {
+            this.deathSummary.remove();
+        }
+        const interval = this.config ? this.config.updateInterval : 0;
+        const cooldown = (death.respawnTick - death.tick) * interval;
+        this.deathSummary = new DeathSummary(death, cooldown, () => this.client.send('respawn'));
+    }
+
+    sendDirection(direction) {
+        this.client.send('direction', direction);
+    }
+
     cleanup() {
-        if (this.ws) {
-            this.ws.close();
+        if (this.client) {
+            this.client.disconnect();
         }
         if (this.controller) {
             this.controller.cleanup();
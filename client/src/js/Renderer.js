@@ -7,6 +7,8 @@ export class Renderer {
         this.gridSize = GRID_SIZE;
         this.cols = 0;
         this.rows = 0;
+        this.walls = false;
+        this.mapWalls = [];
         this.scale = 1;
         this.appleAlpha = 1;
         this.lastTime = performance.now();
@@ -119,17 +121,30 @@ export class Renderer {
         this.canvas.style.margin = 'auto';
     }
 
-    setGameSize(cols, rows) {
+    // boundary 为 walls 时地图边缘是墙，绘制边框
+    setGameSize(cols, rows, boundary) {
         this.cols = cols;
         this.rows = rows;
+        this.walls = boundary === 'walls';
         this.updateCanvasSize();
     }
 
+    // 地图中的墙随欢迎消息下发，之后每帧绘制
+    setMap(map) {
+        this.mapWalls = map ? map.walls || [] : [];
+    }
+
     draw(player, snakes, apples) {
         this.clear();
         this.ctx.save();
         this.ctx.scale(this.scale, this.scale);
 
+        // 绘制地图中的墙
+        this.ctx.fillStyle = '#555';
+        this.mapWalls.forEach(wall => {
+            this.ctx.fillRect(wall.x * this.gridSize, wall.y * this.gridSize, this.gridSize, this.gridSize);
+        });
+
         // 绘制所有苹果
         apples.forEach(apple => this.drawApple(apple));
 
@@ -140,6 +155,49 @@ export class Renderer {
             }
         });
 
+        if (this.walls) {
+            this.ctx.strokeStyle = '#333';
+            this.ctx.lineWidth = 2;
+            this.ctx.strokeRect(0, 0, this.cols * this.gridSize, this.rows * this.gridSize);
+        }
+
+        this.ctx.restore();
+    }
+
+    // 在右上角绘制小地图：所有蛇的头部位置以及当前视野范围
+    drawMinimap(minimap, view) {
+        if (!this.cols || !this.rows || !minimap.length) {
+            return;
+        }
+        const size = 120;
+        const margin = 10;
+        const left = this.canvas.width - size - margin;
+        const top = margin;
+        const scaleX = size / this.cols;
+        const scaleY = size / this.rows;
+
+        this.ctx.save();
+        this.ctx.fillStyle = 'rgba(255, 255, 255, 0.8)';
+        this.ctx.fillRect(left, top, size, size);
+        this.ctx.strokeStyle = '#999';
+        this.ctx.strokeRect(left, top, size, size);
+
+        minimap.forEach(snake => {
+            // 蛇越长点越大
+            const radius = Math.min(4, 1 + snake.length / 20);
+            this.ctx.fillStyle = snake.isAI ? '#666' : '#FF0000';
+            this.ctx.fillRect(left + snake.x * scaleX - radius / 2, top + snake.y * scaleY - radius / 2, radius, radius);
+        });
+
+        if (view) {
+            // 视野可能跨越地图边界，截取到小地图范围内绘制
+            const minX = Math.max(0, view.minX);
+            const minY = Math.max(0, view.minY);
+            const maxX = Math.min(this.cols - 1, view.maxX);
+            const maxY = Math.min(this.rows - 1, view.maxY);
+            this.ctx.strokeStyle = '#4CAF50';
+            this.ctx.strokeRect(left + minX * scaleX, top + minY * scaleY, (maxX - minX + 1) * scaleX, (maxY - minY + 1) * scaleY);
+        }
         this.ctx.restore();
     }
-}
\ No newline at end of file
+}
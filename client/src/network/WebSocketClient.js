@@ -1,31 +1,49 @@
 // WebSocket客户端，负责处理与服务器的通信
+// 服务端的每条消息都是 {type, v, payload} 信封，按type分发给通过on()注册的处理函数
 export class WebSocketClient {
+    // url 可以是字符串，也可以是每次连接时调用的函数，便于重连时带上最新的参数
     constructor(url) {
         this.url = url;
         this.ws = null;
         this.messageHandlers = new Map();
         this.reconnectAttempts = 0;
         this.maxReconnectAttempts = 5;
+        this.closing = false;
+        // 每次连接(包括重连)成功时调用
+        this.onOpen = null;
     }
 
     connect() {
+        this.closing = false;
         return new Promise((resolve, reject) => {
-            this.ws = new WebSocket(this.url);
+            const url = typeof this.url === 'function' ? this.url() : this.url;
+            this.ws = new WebSocket(url);
 
             this.ws.onopen = () => {
                 console.log('已连接到服务器');
                 this.reconnectAttempts = 0;
+                if (this.onOpen) {
+                    this.onOpen();
+                }
                 resolve();
             };
 
             this.ws.onmessage = (event) => {
-                const data = JSON.parse(event.data);
+                let data;
+                try {
+                    data = JSON.parse(event.data);
+                } catch (error) {
+                    console.error('无法解析的消息:', error);
+                    return;
+                }
                 this.handleMessage(data);
             };
 
             this.ws.onclose = () => {
                 console.log('与服务器断开连接');
-                this.attemptReconnect();
+                if (!this.closing) {
+                    this.attemptReconnect();
+                }
             };
 
             this.ws.onerror = (error) => {
@@ -39,7 +57,7 @@ export class WebSocketClient {
         if (this.reconnectAttempts < this.maxReconnectAttempts) {
             this.reconnectAttempts++;
             console.log(`尝试重新连接 (${this.reconnectAttempts}/${this.maxReconnectAttempts})`);
-            setTimeout(() => this.connect(), 1000 * Math.pow(2, this.reconnectAttempts));
+            setTimeout(() => this.connect().catch(() => {}), 1000 * Math.pow(2, this.reconnectAttempts - 1));
         } else {
             console.error('重连次数超过最大限制');
         }
@@ -60,11 +78,12 @@ export class WebSocketClient {
     handleMessage(data) {
         const handler = this.messageHandlers.get(data.type);
         if (handler) {
-            handler(data.payload);
+            handler(data.payload, data);
         }
     }
 
     disconnect() {
+        this.closing = true;
         if (this.ws) {
             this.ws.close();
             this.ws = null;
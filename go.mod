@@ -2,4 +2,10 @@ module snakesol
 
 go 1.19
 
-require github.com/gorilla/websocket v1.5.3
+require (
+	github.com/gorilla/websocket v1.5.3
+	go.etcd.io/bbolt v1.3.9
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.10.0 // indirect
@@ -0,0 +1,352 @@
+// Package config 负责加载服务器配置
+//
+// 配置按以下优先级合并（后者覆盖前者）：
+// 默认值 < 配置文件(-config) < SNAKESOL_* 环境变量 < 命令行参数
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"snakesol/internal/game"
+	"snakesol/internal/http"
+	"snakesol/internal/network"
+	"snakesol/internal/room"
+	"snakesol/internal/stats"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 环境变量前缀
+const EnvPrefix = "SNAKESOL_"
+
+// Config 服务器的完整配置
+type Config struct {
+	Game  *game.GameConfig `yaml:"game"`
+	HTTP  *http.Config     `yaml:"http"`
+	WS    *network.Config  `yaml:"ws"`
+	Room  *room.Config     `yaml:"room"`
+	Stats *stats.Config    `yaml:"stats"`
+	Rooms []RoomSpec       `yaml:"rooms"`
+}
+
+// RoomSpec 配置文件中预先定义的常驻房间
+type RoomSpec struct {
+	ID string `yaml:"id"`
+	// Overrides 在全局game配置基础上覆盖的字段
+	Overrides yaml.Node `yaml:"game"`
+	// Game 合并后的房间游戏配置，由Load填充
+	Game *game.GameConfig `yaml:"-"`
+}
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Game:  game.DefaultConfig(),
+		HTTP:  http.DefaultConfig(),
+		WS:    network.DefaultConfig(),
+		Room:  room.DefaultConfig(),
+		Stats: stats.DefaultConfig(),
+	}
+}
+
+// Validate 检查所有配置项
+func (c *Config) Validate() error {
+	if err := c.Game.Validate(); err != nil {
+		return fmt.Errorf("game 配置无效: %w", err)
+	}
+	if err := c.HTTP.Validate(); err != nil {
+		return fmt.Errorf("http 配置无效: %w", err)
+	}
+	if err := c.WS.Validate(); err != nil {
+		return fmt.Errorf("ws 配置无效: %w", err)
+	}
+	if err := c.Room.Validate(); err != nil {
+		return fmt.Errorf("room 配置无效: %w", err)
+	}
+	if err := c.Stats.Validate(); err != nil {
+		return fmt.Errorf("stats 配置无效: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, spec := range c.Rooms {
+		if spec.ID == c.Room.DefaultRoom || seen[spec.ID] {
+			return fmt.Errorf("房间 %q 重复定义", spec.ID)
+		}
+		seen[spec.ID] = true
+		if err := spec.Game.Validate(); err != nil {
+			return fmt.Errorf("房间 %q 的game配置无效: %w", spec.ID, err)
+		}
+	}
+	return nil
+}
+
+// Load 解析命令行参数，并按优先级合并默认值、配置文件、环境变量和命令行参数
+func Load(name string, args []string) (*Config, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	path := fs.String("config", "", "配置文件路径(YAML或JSON)")
+	port := fs.String("port", "", "服务器监听端口，等价于 -http.addr :<port>")
+
+	// 每个配置项都注册为一个命令行参数，解析后再统一应用，保证其优先级最高
+	cfg := Default()
+	fields := collectFields(cfg)
+	flagValues := make(map[string]string)
+	for _, f := range fields {
+		fs.Var(&rawValue{name: f.key, values: flagValues, def: f.current()}, f.flagName(), "覆盖配置项 "+f.key+"，环境变量 "+f.envName())
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		if err := loadFile(*path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	// 配置文件可能替换了内部指针，重新收集字段
+	fields = collectFields(cfg)
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.envName()); ok {
+			if err := f.set(v); err != nil {
+				return nil, fmt.Errorf("环境变量 %s: %w", f.envName(), err)
+			}
+		}
+	}
+	for _, f := range fields {
+		if v, ok := flagValues[f.key]; ok {
+			if err := f.set(v); err != nil {
+				return nil, fmt.Errorf("参数 -%s: %w", f.flagName(), err)
+			}
+		}
+	}
+	if *port != "" {
+		cfg.HTTP.Addr = ":" + *port
+	}
+
+	// 预定义房间的配置以最终的全局game配置为基础
+	for i := range cfg.Rooms {
+		spec := &cfg.Rooms[i]
+		gameConfig := *cfg.Game
+		if !spec.Overrides.IsZero() {
+			if err := decodeNode(&spec.Overrides, &gameConfig); err != nil {
+				return nil, fmt.Errorf("解析房间 %q 的配置失败: %w", spec.ID, err)
+			}
+		}
+		spec.Game = &gameConfig
+	}
+
+	// 地图的尺寸覆盖cols和rows，因此在校验之前加载
+	if err := cfg.Game.LoadMap(); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Rooms {
+		if err := cfg.Rooms[i].Game.LoadMap(); err != nil {
+			return nil, fmt.Errorf("房间 %q: %w", cfg.Rooms[i].ID, err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile 从YAML或JSON文件中读取配置，文件中未出现的字段保留原值
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	// JSON是YAML的子集，两种格式统一交给YAML解析器处理
+	if err := decodeStrict(data, cfg); err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", filepath.Base(path), err)
+	}
+	if cfg.Game == nil {
+		cfg.Game = game.DefaultConfig()
+	}
+	if cfg.HTTP == nil {
+		cfg.HTTP = http.DefaultConfig()
+	}
+	if cfg.WS == nil {
+		cfg.WS = network.DefaultConfig()
+	}
+	if cfg.Room == nil {
+		cfg.Room = room.DefaultConfig()
+	}
+	if cfg.Stats == nil {
+		cfg.Stats = stats.DefaultConfig()
+	}
+	return nil
+}
+
+// decodeStrict 解析YAML，出现未知字段时返回错误，避免拼错的配置项被静默忽略；空文件不是错误
+func decodeStrict(data []byte, v interface{}) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// decodeNode 以同样的规则解析配置文件中的一个节点
+// yaml.Node.Decode不检查未知字段，因此先重新编码再解析
+func decodeNode(node *yaml.Node, v interface{}) error {
+	data, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return decodeStrict(data, v)
+}
+
+// field 描述一个可被环境变量和命令行参数覆盖的配置项
+type field struct {
+	key   string // 形如 game.initial_ai_count
+	value reflect.Value
+}
+
+// flagName 返回命令行参数名，如 game.initial-ai-count
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+// envName 返回环境变量名，如 SNAKESOL_GAME_INITIAL_AI_COUNT
+func (f field) envName() string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(f.key, ".", "_"))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set 将字符串解析为字段对应的类型并写入
+func (f field) set(s string) error {
+	v := f.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		parts := make([]string, 0)
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				parts = append(parts, p)
+			}
+		}
+		v.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("不支持的配置类型 %s", v.Type())
+	}
+	return nil
+}
+
+// current 返回字段当前值的字符串形式
+func (f field) current() string {
+	v := f.value
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// collectFields 遍历配置结构体，收集所有带yaml标签的叶子字段
+func collectFields(cfg *Config) []field {
+	var fields []field
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return
+			}
+			v = v.Elem()
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if tag == "" || tag == "-" {
+				continue
+			}
+			key := tag
+			if prefix != "" {
+				key = prefix + "." + tag
+			}
+			fv := v.Field(i)
+			// 列表类配置(如rooms)只能通过配置文件设置
+			if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			if (fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct) || fv.Kind() == reflect.Struct {
+				walk(key, fv)
+				continue
+			}
+			fields = append(fields, field{key: key, value: fv})
+		}
+	}
+	walk("", reflect.ValueOf(cfg))
+	return fields
+}
+
+// rawValue 记录命令行中出现的原始参数值，待配置文件加载后再应用
+type rawValue struct {
+	name   string
+	values map[string]string
+	def    string
+}
+
+func (r *rawValue) String() string {
+	if r == nil {
+		return ""
+	}
+	if v, ok := r.values[r.name]; ok {
+		return v
+	}
+	return r.def
+}
+
+func (r *rawValue) Set(s string) error {
+	r.values[r.name] = s
+	return nil
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"snakesol/internal/game"
+	"snakesol/internal/http"
+)
+
+// writeConfig 把配置写入临时文件，返回文件路径
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string // 为空表示期望成功
+	}{
+		{"空文件", "", ""},
+		{"只有注释", "# 使用默认配置\n", ""},
+		{"已知字段", "game:\n  cols: 40\n  rows: 30\n", ""},
+		{"JSON", `{"game": {"cols": 40}}`, ""},
+		{"拼错的字段", "game:\n  colz: 40\n", "colz"},
+		{"未知的分组", "gmae:\n  cols: 40\n", "gmae"},
+		{"房间中未知的字段", "rooms:\n  - id: big\n    size: 2\n", "size"},
+		{"房间覆盖中拼错的字段", "rooms:\n  - id: big\n    game:\n      colz: 200\n", "colz"},
+		{"类型错误", "game:\n  cols: many\n", "many"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load("test", []string{"-config", writeConfig(t, tt.content)})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load() 返回 %v，期望成功", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() 返回 %v，期望包含 %q 的错误", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name            string
+		file, env, flag string // game.cols的各个来源，为空表示不设置
+		want            int
+	}{
+		{"默认值", "", "", "", game.DefaultConfig().Cols},
+		{"配置文件覆盖默认值", "40", "", "", 40},
+		{"环境变量覆盖配置文件", "40", "50", "", 50},
+		{"命令行参数覆盖环境变量", "", "50", "60", 60},
+		{"命令行参数覆盖所有来源", "40", "50", "60", 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 预定义房间以最终的全局配置为基础
+			content := "rooms:\n  - id: big\n"
+			if tt.file != "" {
+				content += "game:\n  cols: " + tt.file + "\n"
+			}
+			args := []string{"-config", writeConfig(t, content)}
+			if tt.env != "" {
+				t.Setenv("SNAKESOL_GAME_COLS", tt.env)
+			}
+			if tt.flag != "" {
+				args = append(args, "-game.cols", tt.flag)
+			}
+			cfg, err := Load("test", args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Game.Cols != tt.want || cfg.Rooms[0].Game.Cols != tt.want {
+				t.Fatalf("cols 为 %d、房间的cols为 %d，期望都为 %d", cfg.Game.Cols, cfg.Rooms[0].Game.Cols, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPort(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  string // SNAKESOL_HTTP_ADDR，为空表示不设置
+		want string
+	}{
+		{"默认值", nil, "", http.DefaultConfig().Addr},
+		{"-port", []string{"-port", "9000"}, "", ":9000"},
+		{"-port覆盖环境变量", []string{"-port", "9000"}, "127.0.0.1:7000", ":9000"},
+		{"-port覆盖-http.addr", []string{"-http.addr", "127.0.0.1:7000", "-port", "9000"}, "", ":9000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("SNAKESOL_HTTP_ADDR", tt.env)
+			}
+			cfg, err := Load("test", tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.HTTP.Addr != tt.want {
+				t.Fatalf("http.addr 为 %q，期望 %q", cfg.HTTP.Addr, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{"rows为0", []string{"-game.rows", "0"}, nil, "rows 必须大于0"},
+		{"负的更新间隔", nil, map[string]string{"SNAKESOL_GAME_UPDATE_INTERVAL": "-1"}, "update_interval 必须大于0"},
+		{"最大AI数量小于初始数量", []string{"-game.initial-ai-count", "10", "-game.max-ai-count", "5"}, nil, "max_ai_count(5) 不能小于 initial_ai_count(10)"},
+		{"无法解析的环境变量", nil, map[string]string{"SNAKESOL_GAME_COLS": "many"}, "SNAKESOL_GAME_COLS"},
+		{"无法解析的参数", []string{"-ws.write-timeout", "soon"}, nil, "-ws.write-timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load("test", tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load() 返回 %v，期望包含 %q 的错误", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	// 示例配置中的地图路径相对于仓库根目录
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg, err := Load("test", []string{"-config", "config.example.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Rooms) == 0 {
+		t.Fatal("示例配置中的房间没有加载")
+	}
+}
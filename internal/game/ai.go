@@ -1,28 +1,37 @@
 package game
 
 import (
-	"fmt"
 	"math"
 )
 
 // AIController 处理AI蛇的决策逻辑
 type AIController struct {
-	snake  *Snake
+	snake  SnakeInfo
 	config *GameConfig
+	view   *ViewInfo // 本次决策中的视野信息，第一次使用时计算
 }
 
 // NewAIController 创建新的AI控制器
-func NewAIController(snake *Snake, config *GameConfig) *AIController {
+func NewAIController(snake SnakeInfo, config *GameConfig) *AIController {
 	return &AIController{
 		snake:  snake,
 		config: config,
 	}
 }
 
+// viewInfo 返回AI蛇的视野信息，同一次决策中局面不变，只计算一次
+func (ai *AIController) viewInfo(world *WorldView) ViewInfo {
+	if ai.view == nil {
+		view := world.View(ai.snake)
+		ai.view = &view
+	}
+	return *ai.view
+}
+
 // DecideNextMove 决定AI蛇的下一步移动方向
-func (ai *AIController) DecideNextMove(gameState *GameState) Direction {
+func (ai *AIController) DecideNextMove(world *WorldView) Direction {
 	// 获取当前可用的移动方向
-	availableDirections := ai.getAvailableDirections(gameState)
+	availableDirections := ai.getAvailableDirections(world)
 	if len(availableDirections) == 0 {
 		return ai.snake.Direction // 如果没有安全的方向，保持当前方向
 	}
@@ -32,7 +41,7 @@ func (ai *AIController) DecideNextMove(gameState *GameState) Direction {
 	bestScore := float64(-1000000)
 
 	for _, dir := range availableDirections {
-		score := ai.evaluateDirection(dir, gameState)
+		score := ai.evaluateDirection(dir, world)
 		if score > bestScore {
 			bestScore = score
 			bestDirection = dir
@@ -43,7 +52,7 @@ func (ai *AIController) DecideNextMove(gameState *GameState) Direction {
 }
 
 // getAvailableDirections 获取安全的移动方向
-func (ai *AIController) getAvailableDirections(gameState *GameState) []Direction {
+func (ai *AIController) getAvailableDirections(world *WorldView) []Direction {
 	directions := []Direction{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
 	safeDirections := make([]Direction, 0)
 
@@ -56,39 +65,13 @@ func (ai *AIController) getAvailableDirections(gameState *GameState) []Direction
 			continue
 		}
 
-		nextX := (ai.snake.X + dir.X + ai.config.Cols) % ai.config.Cols
-		nextY := (ai.snake.Y + dir.Y + ai.config.Rows) % ai.config.Rows
-
-		// 检查是否会撞到自己
-		safe := true
-		for _, segment := range ai.snake.Body {
-			if nextX == segment.X && nextY == segment.Y {
-				safe = false
-				break
-			}
-		}
-		if !safe {
+		// 检查是否会撞墙
+		next, ok := ai.config.step(ai.snake.X, ai.snake.Y, dir)
+		if !ok {
 			continue
 		}
-
-		// 检查是否会撞到其他蛇
-		for _, otherSnake := range gameState.snakes {
-			if otherSnake.Dead || otherSnake == ai.snake {
-				continue
-			}
-			// 检查头部碰撞
-			if nextX == otherSnake.X && nextY == otherSnake.Y {
-				safe = false
-				break
-			}
-			// 检查身体碰撞
-			for _, segment := range otherSnake.Body {
-				if nextX == segment.X && nextY == segment.Y {
-					safe = false
-					break
-				}
-			}
-		}
+		// 检查是否会撞到自己或其他蛇的头部和身体
+		safe := !world.Occupied(next)
 
 		if safe {
 			safeDirections = append(safeDirections, dir)
@@ -99,16 +82,16 @@ func (ai *AIController) getAvailableDirections(gameState *GameState) []Direction
 }
 
 // evaluateDirection 评估某个方向的得分
-func (ai *AIController) evaluateDirection(dir Direction, gameState *GameState) float64 {
-	nextX := (ai.snake.X + dir.X + ai.config.Cols) % ai.config.Cols
-	nextY := (ai.snake.Y + dir.Y + ai.config.Rows) % ai.config.Rows
+func (ai *AIController) evaluateDirection(dir Direction, world *WorldView) float64 {
+	next, _ := ai.config.step(ai.snake.X, ai.snake.Y, dir)
+	nextX, nextY := next.X, next.Y
 
 	// 获取性格权重并根据局势动态调整
 	baseWeights := GetPersonalityWeights(ai.snake.Personality)
-	weights := ai.adjustWeightsByGameState(baseWeights, gameState)
+	weights := ai.adjustWeightsByGameState(baseWeights, world)
 
 	// 获取视野范围内的信息
-	viewInfo := ai.snake.GetViewInfo(gameState)
+	viewInfo := ai.viewInfo(world)
 
 	// 基础得分
 	score := 0.0
@@ -124,7 +107,7 @@ func (ai *AIController) evaluateDirection(dir Direction, gameState *GameState) f
 	score += (float64(ViewWidth+ViewHeight) - minFoodDist) * 10 * weights.FoodWeight
 
 	// 2. 空间评分 - 根据视野内的空间评估
-	spaceScore := ai.evaluateSpace(nextX, nextY, gameState)
+	spaceScore := ai.evaluateSpace(nextX, nextY, world)
 	score += spaceScore * 5 * weights.SpaceWeight
 
 	// 3. 生存评分 - 根据视野内的威胁评估
@@ -140,9 +123,9 @@ func (ai *AIController) evaluateDirection(dir Direction, gameState *GameState) f
 
 	// 4. 攻击评分 - 根据视野内的攻击机会评估
 	attackScore := 0.0
-	if len(ai.snake.Body) > 10 {
+	if ai.snake.Length > 10 {
 		for _, otherSnake := range viewInfo.Snakes {
-			if len(otherSnake.Body) < len(ai.snake.Body) {
+			if otherSnake.Length < ai.snake.Length {
 				dist := ai.distance(nextX, nextY, otherSnake.X, otherSnake.Y)
 				if dist < 5 { // 根据性格评估攻击价值
 					attackScore += (5 - dist) * 20
@@ -154,19 +137,19 @@ func (ai *AIController) evaluateDirection(dir Direction, gameState *GameState) f
 
 	// 5. 机动性评分 - 特别适用于游击型
 	if weights.MobilityWeight > 0 {
-		mobilityScore := float64(len(ai.getAvailableDirections(gameState)))
+		mobilityScore := float64(len(ai.getAvailableDirections(world)))
 		score += mobilityScore * 10 * weights.MobilityWeight
 	}
 
 	// 6. 陷阱评分 - 特别适用于陷阱型
 	if weights.TrapWeight > 0 {
-		trapScore := ai.evaluateTrapPotential(nextX, nextY, gameState)
+		trapScore := ai.evaluateTrapPotential(nextX, nextY, world)
 		score += trapScore * weights.TrapWeight
 	}
 
 	// 7. 协作评分 - 特别适用于协作型
 	if weights.CooperateWeight > 0 {
-		cooperateScore := ai.evaluateCooperation(nextX, nextY, gameState)
+		cooperateScore := ai.evaluateCooperation(nextX, nextY, world)
 		score += cooperateScore * weights.CooperateWeight
 	}
 
@@ -174,32 +157,22 @@ func (ai *AIController) evaluateDirection(dir Direction, gameState *GameState) f
 }
 
 // evaluateSpace 评估某个位置的可用空间
-func (ai *AIController) evaluateSpace(x, y int, gameState *GameState) float64 {
-	visited := make(map[string]bool)
-	space := ai.floodFill(x, y, gameState, visited)
+func (ai *AIController) evaluateSpace(x, y int, world *WorldView) float64 {
+	visited := make(map[Position]bool, 64)
+	space := ai.floodFill(x, y, world, visited)
 	return float64(space)
 }
 
 // floodFill 使用泛洪算法计算可用空间
-func (ai *AIController) floodFill(x, y int, gameState *GameState, visited map[string]bool) int {
-	key := fmt.Sprintf("%d,%d", x, y)
+func (ai *AIController) floodFill(x, y int, world *WorldView, visited map[Position]bool) int {
+	key := Position{X: x, Y: y}
 	if visited[key] {
 		return 0
 	}
 
 	// 检查是否是障碍物
-	for _, snake := range gameState.snakes {
-		if snake.Dead {
-			continue
-		}
-		if x == snake.X && y == snake.Y {
-			return 0
-		}
-		for _, segment := range snake.Body {
-			if x == segment.X && y == segment.Y {
-				return 0
-			}
-		}
+	if world.Occupied(key) {
+		return 0
 	}
 
 	visited[key] = true
@@ -213,16 +186,18 @@ func (ai *AIController) floodFill(x, y int, gameState *GameState, visited map[st
 	// 递归搜索四个方向
 	directions := []Direction{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
 	for _, dir := range directions {
-		nextX := (x + dir.X + ai.config.Cols) % ai.config.Cols
-		nextY := (y + dir.Y + ai.config.Rows) % ai.config.Rows
-		space += ai.floodFill(nextX, nextY, gameState, visited)
+		next, ok := ai.config.step(x, y, dir)
+		if !ok {
+			continue
+		}
+		space += ai.floodFill(next.X, next.Y, world, visited)
 	}
 
 	return space
 }
 
 // evaluateTrapPotential 评估某个位置设置陷阱的潜力
-func (ai *AIController) evaluateTrapPotential(x, y int, gameState *GameState) float64 {
+func (ai *AIController) evaluateTrapPotential(x, y int, world *WorldView) float64 {
     // 基础陷阱得分
     trapScore := 0.0
 
@@ -230,26 +205,14 @@ func (ai *AIController) evaluateTrapPotential(x, y int, gameState *GameState) fl
     passages := 0
     directions := []Direction{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
     for _, dir := range directions {
-        nextX := (x + dir.X + ai.config.Cols) % ai.config.Cols
-        nextY := (y + dir.Y + ai.config.Rows) % ai.config.Rows
+        // 墙不算通道
+        next, ok := ai.config.step(x, y, dir)
+        if !ok {
+            continue
+        }
         
         // 检查该方向是否有障碍物
-        hasObstacle := false
-        for _, snake := range gameState.snakes {
-            if snake.Dead {
-                continue
-            }
-            if nextX == snake.X && nextY == snake.Y {
-                hasObstacle = true
-                break
-            }
-            for _, segment := range snake.Body {
-                if nextX == segment.X && nextY == segment.Y {
-                    hasObstacle = true
-                    break
-                }
-            }
-        }
+        hasObstacle := world.Occupied(next)
         
         if !hasObstacle {
             passages++
@@ -261,15 +224,11 @@ func (ai *AIController) evaluateTrapPotential(x, y int, gameState *GameState) fl
         trapScore += float64(50 * (3 - passages))
 
         // 检查附近是否有其他蛇
-        for _, otherSnake := range gameState.snakes {
-            if otherSnake.Dead || otherSnake == ai.snake {
-                continue
-            }
-            
+        for _, otherSnake := range ai.viewInfo(world).Snakes {
             dist := ai.distance(x, y, otherSnake.X, otherSnake.Y)
             if dist < 5 {
                 // 如果附近有较小的蛇，增加陷阱得分
-                if len(otherSnake.Body) < len(ai.snake.Body) {
+                if otherSnake.Length < ai.snake.Length {
                     trapScore += (5 - dist) * 30
                 } else {
                     // 如果附近有较大的蛇，降低陷阱得分
@@ -279,7 +238,7 @@ func (ai *AIController) evaluateTrapPotential(x, y int, gameState *GameState) fl
         }
 
         // 确保有逃生路线
-        escapeSpace := ai.evaluateSpace(x, y, gameState)
+        escapeSpace := ai.evaluateSpace(x, y, world)
         if escapeSpace > 10 {
             trapScore += float64(escapeSpace) * 2
         } else {
@@ -292,23 +251,19 @@ func (ai *AIController) evaluateTrapPotential(x, y int, gameState *GameState) fl
 
 // distance 计算两点之间的曼哈顿距离
 func (ai *AIController) distance(x1, y1, x2, y2 int) float64 {
-	dx := math.Abs(float64(x1 - x2))
-	dy := math.Abs(float64(y1 - y2))
-	// 考虑游戏场地是环形的情况
-	dx = math.Min(dx, float64(ai.config.Cols)-dx)
-	dy = math.Min(dy, float64(ai.config.Rows)-dy)
-	return dx + dy
+	// 环形地图上考虑绕行的情况
+	return float64(ai.config.distance(x1, y1, x2, y2))
 }
 
 // evaluateCooperation 评估协作行为的得分
-func (ai *AIController) evaluateCooperation(x, y int, gameState *GameState) float64 {
+func (ai *AIController) evaluateCooperation(x, y int, world *WorldView) float64 {
 	cooperateScore := 0.0
 
 	// 获取视野范围内的信息
-	viewInfo := ai.snake.GetViewInfo(gameState)
+	viewInfo := ai.viewInfo(world)
 
 	// 寻找同为协作型的其他蛇
-	cooperativeSnakes := make([]*Snake, 0)
+	cooperativeSnakes := make([]SnakeInfo, 0)
 	for _, otherSnake := range viewInfo.Snakes {
 		if otherSnake.Personality == Cooperative {
 			cooperativeSnakes = append(cooperativeSnakes, otherSnake)
@@ -321,11 +276,11 @@ func (ai *AIController) evaluateCooperation(x, y int, gameState *GameState) floa
 	}
 
 	// 寻找目标蛇（非协作型且体型较小的蛇）
-	var targetSnake *Snake
-	for _, snake := range viewInfo.Snakes {
-		if snake.Personality != Cooperative && len(snake.Body) < len(ai.snake.Body) {
-			if targetSnake == nil || len(snake.Body) < len(targetSnake.Body) {
-				targetSnake = snake
+	var targetSnake *SnakeInfo
+	for i, snake := range viewInfo.Snakes {
+		if snake.Personality != Cooperative && snake.Length < ai.snake.Length {
+			if targetSnake == nil || snake.Length < targetSnake.Length {
+				targetSnake = &viewInfo.Snakes[i]
 			}
 		}
 	}
@@ -385,12 +340,12 @@ func (ai *AIController) calculateAngle(x1, y1, x2, y2, x3, y3 int) float64 {
 }
 
 // adjustWeightsByGameState 根据游戏局势动态调整权重
-func (ai *AIController) adjustWeightsByGameState(baseWeights PersonalityWeights, gameState *GameState) PersonalityWeights {
+func (ai *AIController) adjustWeightsByGameState(baseWeights PersonalityWeights, world *WorldView) PersonalityWeights {
 	adjustedWeights := baseWeights
 
 	// 获取当前蛇的状态
-	snakeLength := len(ai.snake.Body)
-	viewInfo := ai.snake.GetViewInfo(gameState)
+	snakeLength := ai.snake.Length
+	viewInfo := ai.viewInfo(world)
 
 	// 1. 根据蛇的长度调整权重
 	if snakeLength < 10 {
@@ -411,7 +366,7 @@ func (ai *AIController) adjustWeightsByGameState(baseWeights PersonalityWeights,
 		dist := ai.distance(ai.snake.X, ai.snake.Y, snake.X, snake.Y)
 		if dist < 10 {
 			nearbySnakes++
-			if len(snake.Body) > snakeLength {
+			if snake.Length > snakeLength {
 				largerSnakes++
 			}
 		}
@@ -0,0 +1,75 @@
+package game
+
+// Viewport 推送给客户端的可见范围，边界可能超出地图，按环形地图处理
+type Viewport struct {
+	MinX int `json:"minX"`
+	MinY int `json:"minY"`
+	MaxX int `json:"maxX"`
+	MaxY int `json:"maxY"`
+}
+
+// MinimapSnake 小地图上的一条蛇，只包含头部位置和长度
+type MinimapSnake struct {
+	X      int  `json:"x"`
+	Y      int  `json:"y"`
+	Length int  `json:"length"`
+	IsAI   bool `json:"isAI"`
+}
+
+// viewport 返回以center为中心、大小为配置视野的可见范围
+func (gs *GameState) viewport(center Position) Viewport {
+	minX := center.X - gs.config.ViewportCols/2
+	minY := center.Y - gs.config.ViewportRows/2
+	return Viewport{
+		MinX: minX,
+		MinY: minY,
+		MaxX: minX + gs.config.ViewportCols - 1,
+		MaxY: minY + gs.config.ViewportRows - 1,
+	}
+}
+
+// inViewport 判断位置是否在可见范围内
+func (gs *GameState) inViewport(view Viewport, pos Position) bool {
+	return isInView(gs.config, pos.X, pos.Y, view.MinX, view.MaxX, view.MinY, view.MaxY)
+}
+
+// snakeInViewport 判断蛇是否有任何一部分在可见范围内
+func (gs *GameState) snakeInViewport(view Viewport, snake *Snake) bool {
+	if gs.inViewport(view, Position{X: snake.X, Y: snake.Y}) {
+		return true
+	}
+	for _, segment := range snake.Body {
+		if gs.inViewport(view, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleApples 返回可见范围内所有苹果位置的集合，调用方需持有锁
+func (gs *GameState) visibleApples(view Viewport) map[Position]bool {
+	set := make(map[Position]bool)
+	for _, apple := range gs.apples {
+		if gs.inViewport(view, apple.Position) {
+			set[apple.Position] = true
+		}
+	}
+	return set
+}
+
+// minimap 生成所有存活蛇的小地图概要，调用方需持有锁
+func (gs *GameState) minimap() []MinimapSnake {
+	minimap := make([]MinimapSnake, 0, len(gs.order))
+	for _, snake := range gs.order {
+		if snake.Dead {
+			continue
+		}
+		minimap = append(minimap, MinimapSnake{
+			X:      snake.X,
+			Y:      snake.Y,
+			Length: len(snake.Body),
+			IsAI:   snake.IsAI,
+		})
+	}
+	return minimap
+}
@@ -0,0 +1,33 @@
+package game
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// BenchmarkUpdateGame 测量不同蛇数量下每个tick的耗时，场地面积随蛇的数量增长
+// AI决策不设时间上限，ai-ms/tick为其中AI决策的平均耗时
+func BenchmarkUpdateGame(b *testing.B) {
+	for _, n := range []int{100, 500, 2000} {
+		b.Run(fmt.Sprintf("snakes=%d", n), func(b *testing.B) {
+			config := DefaultConfig()
+			config.Cols = int(math.Ceil(math.Sqrt(float64(n) * 100)))
+			config.Rows = config.Cols
+			config.InitialAICount, config.MaxAICount = n, n
+			config.AIDeadline = 0
+			config.Seed = 1
+			gs, err := NewGameState(config)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				gs.UpdateGame()
+			}
+			b.StopTimer()
+			b.ReportMetric(gs.Metrics().AIAvgMs, "ai-ms/tick")
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package game
+
+// Protocol 客户端选择的状态同步协议
+type Protocol string
+
+const (
+	// ProtocolFull 每个tick发送完整状态
+	ProtocolFull Protocol = "full"
+	// ProtocolDelta 加入时发送完整快照，之后每个tick只发送增量
+	ProtocolDelta Protocol = "delta"
+)
+
+// ParseProtocol 解析客户端请求的协议，未知值按完整状态处理
+func ParseProtocol(s string) Protocol {
+	if Protocol(s) == ProtocolDelta {
+		return ProtocolDelta
+	}
+	return ProtocolFull
+}
+
+// StateMessage 广播给客户端的完整游戏状态
+type StateMessage struct {
+	Seq    uint64            `json:"seq,omitempty"`
+	Tick   uint64            `json:"tick"`
+	Snakes map[string]*Snake `json:"snakes"`
+	Apples []Position        `json:"apples"`
+	Config *GameConfig       `json:"config"`
+	// View 推送给该客户端的可见范围，为空时表示整个地图
+	View *Viewport `json:"view,omitempty"`
+	// Minimap 所有存活蛇的概要，按配置的间隔推送
+	Minimap []MinimapSnake `json:"minimap,omitempty"`
+}
+
+// DeltaMessage 相对于同一客户端上一条消息的增量状态
+// Seq 在每个客户端内连续递增，客户端发现不连续时应请求重新同步
+type DeltaMessage struct {
+	Seq           uint64         `json:"seq"`
+	Tick          uint64         `json:"tick"`
+	Spawned       []*Snake       `json:"spawned,omitempty"`
+	Moved         []SnakeMove    `json:"moved,omitempty"`
+	Removed       []string       `json:"removed,omitempty"`
+	Died          []string       `json:"died,omitempty"`
+	ApplesAdded   []Position     `json:"applesAdded,omitempty"`
+	ApplesRemoved []Position     `json:"applesRemoved,omitempty"`
+	View          *Viewport      `json:"view"`
+	Minimap       []MinimapSnake `json:"minimap,omitempty"`
+}
+
+// DeathEvent 玩家的蛇死亡，推送给该玩家和跟随它的观众
+type DeathEvent struct {
+	SnakeID     string  `json:"snakeId"`
+	Tick        uint64  `json:"tick"`
+	Length      int     `json:"length"`
+	KillerID    string  `json:"killerId,omitempty"`
+	KillerName  string  `json:"killerName,omitempty"`
+	Score       int     `json:"score"`
+	Kills       int     `json:"kills"`
+	Alive       float64 `json:"alive"`       // 存活时间(秒)
+	RespawnTick uint64  `json:"respawnTick"` // 可以复活的tick
+}
+
+// KillEvent 一条蛇撞上另一条蛇的身体而死亡，推送给所有玩家
+type KillEvent struct {
+	Tick       uint64 `json:"tick"`
+	KillerID   string `json:"killerId"`
+	KillerName string `json:"killerName"`
+	VictimID   string `json:"victimId"`
+	VictimName string `json:"victimName"`
+}
+
+// SnakeMove 蛇前进一格：旧的头部成为身体第一节，身体截断到Length
+type SnakeMove struct {
+	ID        string    `json:"id"`
+	X         int       `json:"x"`
+	Y         int       `json:"y"`
+	Direction Direction `json:"direction"`
+	Length    int       `json:"length"`
+}
+
+// knownSnake 客户端已知的蛇的状态
+type knownSnake struct {
+	head   Position
+	length int
+	frozen bool
+}
+
+// client 接收状态广播的客户端及其同步状态
+// 客户端只接收视野范围内的蛇和苹果，已知状态同样只包含视野内的部分
+type client struct {
+	conn     Connection
+	protocol Protocol
+	follow   string        // 视野跟随的蛇，玩家为自己的蛇，自由视角的观众为空
+	center   Position      // 视野中心，跟随的蛇死亡后保持最后的位置
+	player   PlayerOptions // 玩家选择的昵称(没有选择时为空)和蛇的颜色，复活时沿用
+	respawn  uint64        // 玩家的蛇死亡后可以复活的tick
+	seq      uint64
+	needFull bool
+	snakes   map[string]knownSnake
+	apples   map[Position]bool
+}
+
+// newClient 创建以center为视野中心的客户端，第一次广播时发送完整快照
+func newClient(conn Connection, protocol Protocol, center Position) *client {
+	return &client{
+		conn:     conn,
+		protocol: protocol,
+		center:   center,
+		needFull: true,
+	}
+}
+
+// stateMessage 生成当前的完整游戏状态，调用方需持有锁
+func (gs *GameState) stateMessage() *StateMessage {
+	snakes := make(map[string]*Snake, len(gs.snakes))
+	for id, snake := range gs.snakes {
+		copied := *snake
+		snakes[id] = &copied
+	}
+	positions := make([]Position, len(gs.apples))
+	for i, apple := range gs.apples {
+		positions[i] = apple.Position
+	}
+	return &StateMessage{
+		Tick:   gs.tick,
+		Snakes: snakes,
+		Apples: positions,
+		Config: gs.config,
+	}
+}
+
+// clientState 生成客户端视野范围内的完整状态，调用方需持有锁
+func (gs *GameState) clientState(c *client) *StateMessage {
+	view := gs.viewport(c.center)
+	snakes := make(map[string]*Snake)
+	for _, snake := range gs.order {
+		if gs.snakeInViewport(view, snake) {
+			copied := *snake
+			snakes[snake.ID] = &copied
+		}
+	}
+	positions := make([]Position, 0)
+	for _, apple := range gs.apples {
+		if gs.inViewport(view, apple.Position) {
+			positions = append(positions, apple.Position)
+		}
+	}
+	return &StateMessage{
+		Tick:   gs.tick,
+		Snakes: snakes,
+		Apples: positions,
+		Config: gs.config,
+		View:   &view,
+	}
+}
+
+// broadcastState 在tick结束时向所有客户端推送各自视野内的状态，调用方需持有锁
+func (gs *GameState) broadcastState() {
+	var minimap []MinimapSnake
+	if gs.tick%gs.config.Ticks(gs.config.MinimapInterval) == 0 {
+		minimap = gs.minimap()
+	}
+	var leaderboard *LeaderboardMessage
+	if gs.tick%gs.config.Ticks(gs.config.LeaderboardInterval) == 0 {
+		leaderboard = gs.leaderboard()
+	}
+	for _, c := range gs.clients {
+		gs.broadcastTo(c, minimap, leaderboard)
+	}
+	for _, c := range gs.spectators {
+		gs.broadcastTo(c, minimap, leaderboard)
+	}
+	gs.died = gs.died[:0]
+	gs.deaths = gs.deaths[:0]
+	gs.kills = gs.kills[:0]
+}
+
+// broadcastTo 向一个客户端推送视野内的状态，跟随的蛇的死亡事件、所有击杀事件以及排行榜，调用方需持有锁
+// minimap和leaderboard为空时表示本tick不推送
+func (gs *GameState) broadcastTo(c *client, minimap []MinimapSnake, leaderboard *LeaderboardMessage) {
+	if snake, ok := gs.snakes[c.follow]; ok {
+		c.center = Position{X: snake.X, Y: snake.Y}
+	}
+	switch {
+	case c.protocol == ProtocolFull:
+		state := gs.clientState(c)
+		state.Minimap = minimap
+		c.conn.WriteJSON(state)
+	case c.needFull:
+		gs.sendFull(c)
+	default:
+		delta := gs.delta(c)
+		delta.Minimap = minimap
+		c.conn.WriteJSON(delta)
+	}
+	for _, death := range gs.deaths {
+		if death.SnakeID == c.follow {
+			c.conn.WriteJSON(death)
+		}
+	}
+	for _, kill := range gs.kills {
+		c.conn.WriteJSON(kill)
+	}
+	if leaderboard != nil {
+		c.conn.WriteJSON(leaderboard)
+	}
+}
+
+// sendState 在客户端加入时按其协议发送视野内的完整状态，调用方需持有锁
+func (gs *GameState) sendState(c *client) {
+	if c.protocol == ProtocolDelta {
+		gs.sendFull(c)
+		return
+	}
+	state := gs.clientState(c)
+	state.Minimap = gs.minimap()
+	c.conn.WriteJSON(state)
+}
+
+// sendFull 向增量协议的客户端发送视野内的完整快照，并以此为基准计算之后的增量
+func (gs *GameState) sendFull(c *client) {
+	state := gs.clientState(c)
+	state.Minimap = gs.minimap()
+	c.seq++
+	state.Seq = c.seq
+	c.snakes = make(map[string]knownSnake, len(state.Snakes))
+	for id, snake := range state.Snakes {
+		c.snakes[id] = knownSnake{head: Position{X: snake.X, Y: snake.Y}, length: len(snake.Body), frozen: snake.Frozen}
+	}
+	c.apples = make(map[Position]bool, len(state.Apples))
+	for _, pos := range state.Apples {
+		c.apples[pos] = true
+	}
+	c.needFull = false
+	c.conn.WriteJSON(state)
+}
+
+// delta 计算客户端已知状态与当前视野内状态的差异，并更新已知状态
+func (gs *GameState) delta(c *client) *DeltaMessage {
+	view := gs.viewport(c.center)
+	c.seq++
+	msg := &DeltaMessage{Seq: c.seq, Tick: gs.tick, View: &view}
+
+	visible := make(map[string]bool)
+	for _, snake := range gs.order {
+		if !gs.snakeInViewport(view, snake) {
+			continue
+		}
+		visible[snake.ID] = true
+		head := Position{X: snake.X, Y: snake.Y}
+		known, ok := c.snakes[snake.ID]
+		switch {
+		case ok && known.frozen != snake.Frozen:
+			// 冻结状态变化时发送完整数据
+			copied := *snake
+			msg.Spawned = append(msg.Spawned, &copied)
+		case ok && known.head == head && known.length == len(snake.Body):
+			// 没有变化
+		case ok && len(snake.Body) > 0 && snake.Body[0] == known.head:
+			msg.Moved = append(msg.Moved, SnakeMove{
+				ID:        snake.ID,
+				X:         snake.X,
+				Y:         snake.Y,
+				Direction: snake.Direction,
+				Length:    len(snake.Body),
+			})
+		default:
+			// 新进入视野的蛇，或者无法用一步移动描述的变化，发送完整数据
+			copied := *snake
+			msg.Spawned = append(msg.Spawned, &copied)
+		}
+		c.snakes[snake.ID] = knownSnake{head: head, length: len(snake.Body), frozen: snake.Frozen}
+	}
+
+	// 已知但不再可见的蛇：本tick死亡的标记为died，离开视野或被移除的标记为removed
+	died := make(map[string]bool, len(gs.died))
+	for _, id := range gs.died {
+		died[id] = true
+	}
+	for id := range c.snakes {
+		if visible[id] {
+			continue
+		}
+		if died[id] {
+			msg.Died = append(msg.Died, id)
+		} else {
+			msg.Removed = append(msg.Removed, id)
+		}
+		delete(c.snakes, id)
+	}
+
+	apples := gs.visibleApples(view)
+	for pos := range apples {
+		if !c.apples[pos] {
+			msg.ApplesAdded = append(msg.ApplesAdded, pos)
+			c.apples[pos] = true
+		}
+	}
+	for pos := range c.apples {
+		if !apples[pos] {
+			msg.ApplesRemoved = append(msg.ApplesRemoved, pos)
+			delete(c.apples, pos)
+		}
+	}
+	return msg
+}
+
+// Resync 请求在下一次广播时向客户端发送完整快照，id为玩家的蛇ID或观众ID
+func (gs *GameState) Resync(id string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if c, ok := gs.clients[id]; ok {
+		c.needFull = true
+	}
+	if c, ok := gs.spectators[id]; ok {
+		c.needFull = true
+	}
+}
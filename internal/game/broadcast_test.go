@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// recordConn 记录写入的消息
+type recordConn struct {
+	msgs []interface{}
+}
+
+func (c *recordConn) WriteJSON(v interface{}) error {
+	c.msgs = append(c.msgs, v)
+	return nil
+}
+
+func (c *recordConn) ReadJSON(v interface{}) error { return nil }
+
+func (c *recordConn) Close() error { return nil }
+
+// take 返回并清空已记录的消息
+func (c *recordConn) take() []interface{} {
+	msgs := c.msgs
+	c.msgs = nil
+	return msgs
+}
+
+// shownSnake 客户端根据消息还原的一条蛇
+type shownSnake struct {
+	X, Y      int
+	Direction Direction
+	Body      []Position
+	Frozen    bool
+}
+
+func newShownSnake(snake *Snake) shownSnake {
+	return shownSnake{
+		X:         snake.X,
+		Y:         snake.Y,
+		Direction: snake.Direction,
+		Body:      append([]Position(nil), snake.Body...),
+		Frozen:    snake.Frozen,
+	}
+}
+
+// deltaClient 按增量协议的客户端逻辑还原视野内的状态
+type deltaClient struct {
+	seq     uint64
+	tick    uint64
+	view    *Viewport
+	snakes  map[string]shownSnake
+	apples  map[Position]bool
+	entered int // 增量中新进入视野的蛇
+	left    int // 增量中离开视野的蛇
+}
+
+func (d *deltaClient) applyState(msg *StateMessage) {
+	d.seq, d.tick, d.view = msg.Seq, msg.Tick, msg.View
+	d.snakes = make(map[string]shownSnake, len(msg.Snakes))
+	for id, snake := range msg.Snakes {
+		d.snakes[id] = newShownSnake(snake)
+	}
+	d.apples = make(map[Position]bool, len(msg.Apples))
+	for _, pos := range msg.Apples {
+		d.apples[pos] = true
+	}
+}
+
+func (d *deltaClient) applyDelta(msg *DeltaMessage) error {
+	if msg.Seq != d.seq+1 {
+		return fmt.Errorf("seq %d 不连续，上一条为 %d", msg.Seq, d.seq)
+	}
+	d.seq, d.tick, d.view = msg.Seq, msg.Tick, msg.View
+	for _, snake := range msg.Spawned {
+		if _, ok := d.snakes[snake.ID]; !ok {
+			d.entered++
+		}
+		d.snakes[snake.ID] = newShownSnake(snake)
+	}
+	for _, move := range msg.Moved {
+		snake, ok := d.snakes[move.ID]
+		if !ok {
+			return fmt.Errorf("移动了未知的蛇 %s", move.ID)
+		}
+		body := append([]Position{{X: snake.X, Y: snake.Y}}, snake.Body...)
+		if move.Length > len(body) {
+			return fmt.Errorf("蛇 %s 的长度 %d 超过已知身体 %d", move.ID, move.Length, len(body))
+		}
+		snake.X, snake.Y, snake.Direction, snake.Body = move.X, move.Y, move.Direction, body[:move.Length]
+		d.snakes[move.ID] = snake
+	}
+	for _, ids := range [][]string{msg.Removed, msg.Died} {
+		for _, id := range ids {
+			if _, ok := d.snakes[id]; !ok {
+				return fmt.Errorf("移除了未知的蛇 %s", id)
+			}
+			delete(d.snakes, id)
+		}
+	}
+	d.left += len(msg.Removed)
+	for _, pos := range msg.ApplesAdded {
+		d.apples[pos] = true
+	}
+	for _, pos := range msg.ApplesRemoved {
+		if !d.apples[pos] {
+			return fmt.Errorf("移除了未知的苹果 %v", pos)
+		}
+		delete(d.apples, pos)
+	}
+	return nil
+}
+
+// compare 与同一视野的完整状态比较
+func (d *deltaClient) compare(full *StateMessage) error {
+	if d.tick != full.Tick {
+		return fmt.Errorf("tick %d，期望 %d", d.tick, full.Tick)
+	}
+	if !reflect.DeepEqual(d.view, full.View) {
+		return fmt.Errorf("视野 %v，期望 %v", d.view, full.View)
+	}
+	if len(d.snakes) != len(full.Snakes) {
+		return fmt.Errorf("视野内有 %d 条蛇，期望 %d", len(d.snakes), len(full.Snakes))
+	}
+	for id, snake := range full.Snakes {
+		got, ok := d.snakes[id]
+		if !ok {
+			return fmt.Errorf("缺少蛇 %s", id)
+		}
+		if want := newShownSnake(snake); !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("蛇 %s 为 %+v，期望 %+v", id, got, want)
+		}
+	}
+	apples := make([]Position, 0, len(d.apples))
+	for pos := range d.apples {
+		apples = append(apples, pos)
+	}
+	want := append(make([]Position, 0, len(full.Apples)), full.Apples...)
+	for _, list := range [][]Position{apples, want} {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Y < list[j].Y || list[i].Y == list[j].Y && list[i].X < list[j].X
+		})
+	}
+	if !reflect.DeepEqual(apples, want) {
+		return fmt.Errorf("苹果 %v，期望 %v", apples, want)
+	}
+	return nil
+}
+
+// lastState 返回消息中最后一条完整状态，以及按顺序排列的所有完整状态和增量
+func lastState(msgs []interface{}) (*StateMessage, []interface{}) {
+	var state *StateMessage
+	var updates []interface{}
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *StateMessage:
+			state = m
+			updates = append(updates, m)
+		case *DeltaMessage:
+			updates = append(updates, m)
+		}
+	}
+	return state, updates
+}
+
+func TestDeltaRebuildsFullState(t *testing.T) {
+	for _, boundary := range []Boundary{BoundaryWrap, BoundaryWalls} {
+		t.Run(string(boundary), func(t *testing.T) {
+			config := DefaultConfig()
+			config.Cols, config.Rows = 80, 80
+			config.ViewportCols, config.ViewportRows = 24, 16
+			config.InitialAICount, config.MaxAICount = 40, 40
+			config.Boundary = boundary
+			config.Seed = 7
+			config.AIDeadline = 0
+			gs, err := NewGameState(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 一组观众跟随一条蛇，另一组定期移动视野；每组一个完整协议和一个增量协议
+			follow := gs.order[0].ID
+			type pair struct {
+				fullID, deltaID string
+				full, delta     *recordConn
+				client          *deltaClient
+			}
+			var pairs []*pair
+			for _, target := range []string{follow, ""} {
+				p := &pair{full: &recordConn{}, delta: &recordConn{}, client: &deltaClient{}}
+				p.fullID = gs.AddSpectator(p.full, ProtocolFull, target)
+				p.deltaID = gs.AddSpectator(p.delta, ProtocolDelta, target)
+				pairs = append(pairs, p)
+			}
+			camera := pairs[1]
+
+			for i := 0; i < 400; i++ {
+				if i > 0 && i%25 == 0 {
+					center := Position{X: (i * 13) % config.Cols, Y: (i * 29) % config.Rows}
+					gs.MoveCamera(camera.fullID, center)
+					gs.MoveCamera(camera.deltaID, center)
+				}
+				if i == 200 {
+					gs.Resync(camera.deltaID)
+				}
+				if i > 0 {
+					gs.UpdateGame()
+				}
+				for n, p := range pairs {
+					full, _ := lastState(p.full.take())
+					_, updates := lastState(p.delta.take())
+					if full == nil || len(updates) == 0 {
+						t.Fatalf("tick %d 观众组 %d 没有收到状态", gs.tick, n)
+					}
+					for _, update := range updates {
+						var err error
+						switch m := update.(type) {
+						case *StateMessage:
+							p.client.applyState(m)
+						case *DeltaMessage:
+							err = p.client.applyDelta(m)
+						}
+						if err != nil {
+							t.Fatalf("tick %d 观众组 %d: %v", gs.tick, n, err)
+						}
+					}
+					if err := p.client.compare(full); err != nil {
+						t.Fatalf("tick %d 观众组 %d 还原的状态与完整状态不一致: %v", gs.tick, n, err)
+					}
+				}
+			}
+			for n, p := range pairs {
+				if p.client.entered == 0 || p.client.left == 0 {
+					t.Errorf("观众组 %d 进入视野 %d 次、离开视野 %d 次，期望都大于0", n, p.client.entered, p.client.left)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package game
 
 import (
+	"fmt"
 	"math/rand"
-	"time"
+	"strings"
 )
 
 // 游戏配置
@@ -24,9 +25,8 @@ var (
 )
 
 // getRandName 随机生成一个蛇的称号
-func getRandName() string {
-	rand.Seed(time.Now().UnixNano())
-	name := name_prefixs[rand.Intn(len(name_prefixs))] + names[rand.Intn(len(names))]
+func getRandName(rng *rand.Rand) string {
+	name := name_prefixs[rng.Intn(len(name_prefixs))] + names[rng.Intn(len(names))]
 	return name
 }
 
@@ -51,5 +51,103 @@ func DefaultConfig() *GameConfig {
 		AppleSpawnInterval: 1,
 		// 苹果的存活时间(秒)
 		AppleLifetime: 10,
+		// 玩家视野的列数，视野外的蛇和苹果不会推送给该玩家
+		ViewportCols: 60,
+		// 玩家视野的行数
+		ViewportRows: 60,
+		// 小地图推送的时间间隔(秒)
+		MinimapInterval: 1,
+		// 玩家断线后保留其蛇等待重连的时间(秒)
+		SessionGrace: 10,
+		// 玩家死亡后可以复活前需要等待的时间(秒)
+		RespawnCooldown: 3,
+		// 环形地图，从一侧边缘离开时从对侧进入
+		Boundary: BoundaryWrap,
+		// 不使用地图，场地上没有墙
+		MapFile: "",
+		// AI蛇使用内置的性格策略
+		AIStrategy: StrategyPersonality,
+		// 使用GOMAXPROCS个goroutine并发执行AI决策
+		AIWorkers: 0,
+		// AI决策最多占用100毫秒，留出时间移动和广播
+		AIDeadline: 100,
+		// 头对头相撞时两条蛇都死亡
+		HeadOnRule: HeadOnBoth,
+		// 排行榜推送的时间间隔(秒)
+		LeaderboardInterval: 2,
+		// 排行榜包含的蛇的数量
+		LeaderboardSize: 10,
+		// 随机数种子，0表示使用当前时间
+		Seed: 0,
+		// 录像保存目录，为空时不录像
+		RecordDir: "",
+		// 录像中每隔多少tick写入一次快照，决定回放跳转的速度
+		SnapshotInterval: 50,
 	}
 }
+
+// Ticks 将秒数换算为游戏tick数，至少为1
+func (c *GameConfig) Ticks(seconds int) uint64 {
+	ticks := seconds * 1000 / c.UpdateInterval
+	if ticks < 1 {
+		return 1
+	}
+	return uint64(ticks)
+}
+
+// Validate 检查配置是否合理
+func (c *GameConfig) Validate() error {
+	switch {
+	case c.Cols <= 0:
+		return fmt.Errorf("cols 必须大于0，当前为 %d", c.Cols)
+	case c.Rows <= 0:
+		return fmt.Errorf("rows 必须大于0，当前为 %d", c.Rows)
+	case c.InitialSnakeLength <= 0:
+		return fmt.Errorf("initial_snake_length 必须大于0，当前为 %d", c.InitialSnakeLength)
+	case c.UpdateInterval <= 0:
+		return fmt.Errorf("update_interval 必须大于0，当前为 %d", c.UpdateInterval)
+	case c.AISpawnInterval <= 0:
+		return fmt.Errorf("ai_spawn_interval 必须大于0，当前为 %d", c.AISpawnInterval)
+	case c.AppleSpawnInterval <= 0:
+		return fmt.Errorf("apple_spawn_interval 必须大于0，当前为 %d", c.AppleSpawnInterval)
+	case c.AppleLifetime <= 0:
+		return fmt.Errorf("apple_lifetime 必须大于0，当前为 %d", c.AppleLifetime)
+	case c.InitialAICount < 0:
+		return fmt.Errorf("initial_ai_count 不能为负数，当前为 %d", c.InitialAICount)
+	case c.MaxAICount < c.InitialAICount:
+		return fmt.Errorf("max_ai_count(%d) 不能小于 initial_ai_count(%d)", c.MaxAICount, c.InitialAICount)
+	case c.ViewportCols <= 0:
+		return fmt.Errorf("viewport_cols 必须大于0，当前为 %d", c.ViewportCols)
+	case c.ViewportRows <= 0:
+		return fmt.Errorf("viewport_rows 必须大于0，当前为 %d", c.ViewportRows)
+	case c.MinimapInterval <= 0:
+		return fmt.Errorf("minimap_interval 必须大于0，当前为 %d", c.MinimapInterval)
+	case c.SessionGrace < 0:
+		return fmt.Errorf("session_grace 不能为负数，当前为 %d", c.SessionGrace)
+	case c.RespawnCooldown < 0:
+		return fmt.Errorf("respawn_cooldown 不能为负数，当前为 %d", c.RespawnCooldown)
+	case c.Boundary != BoundaryWrap && c.Boundary != BoundaryWalls:
+		return fmt.Errorf("boundary 只能是 wrap 或 walls，当前为 %q", c.Boundary)
+	case !hasStrategy(c.AIStrategy):
+		return fmt.Errorf("ai_strategy 只能是 %s 之一，当前为 %q", strings.Join(Strategies(), "、"), c.AIStrategy)
+	case c.AIWorkers < 0:
+		return fmt.Errorf("ai_workers 不能为负数，当前为 %d", c.AIWorkers)
+	case c.AIDeadline < 0:
+		return fmt.Errorf("ai_deadline 不能为负数，当前为 %d", c.AIDeadline)
+	case c.AIDeadline > c.UpdateInterval:
+		return fmt.Errorf("ai_deadline(%d) 不能大于 update_interval(%d)", c.AIDeadline, c.UpdateInterval)
+	case c.HeadOnRule != HeadOnBoth && c.HeadOnRule != HeadOnLonger:
+		return fmt.Errorf("head_on_rule 只能是 both 或 longer，当前为 %q", c.HeadOnRule)
+	case c.LeaderboardInterval <= 0:
+		return fmt.Errorf("leaderboard_interval 必须大于0，当前为 %d", c.LeaderboardInterval)
+	case c.LeaderboardSize <= 0:
+		return fmt.Errorf("leaderboard_size 必须大于0，当前为 %d", c.LeaderboardSize)
+	case c.SnapshotInterval <= 0:
+		return fmt.Errorf("snapshot_interval 必须大于0，当前为 %d", c.SnapshotInterval)
+	case c.Map != nil && c.Map.spawnCells() < c.InitialAICount:
+		return fmt.Errorf("地图 %s 只有 %d 个出生位置，少于 initial_ai_count(%d)", c.MapFile, c.Map.spawnCells(), c.InitialAICount)
+	case c.InitialAICount*c.InitialSnakeLength > c.Cols*c.Rows/2:
+		return fmt.Errorf("initial_ai_count(%d) 对于 %dx%d 的场地过多", c.InitialAICount, c.Cols, c.Rows)
+	}
+	return nil
+}
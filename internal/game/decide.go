@@ -0,0 +1,191 @@
+package game
+
+import (
+	"log"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// aiTimeoutLogInterval 输出AI决策超时汇总日志的最短间隔(秒)
+const aiTimeoutLogInterval = 10
+
+// aiJob 一条AI蛇在本tick中的决策任务
+type aiJob struct {
+	snake    *Snake
+	strategy Strategy
+	self     SnakeInfo
+	dir      Direction
+	done     bool
+}
+
+// decideAI 生成本tick的只读局面，让每条存活的AI蛇的策略选择方向，调用方需持有锁
+//
+// 策略只读取WorldView和自己的状态，因此可以并发执行；结果按蛇加入游戏的顺序应用。
+// 超过ai_deadline仍未完成决策的蛇保持当前方向。超时的决策可能仍在后台运行，
+// 因此有状态的策略在复制出的实例上决策，超时时蛇保留原来的实例；
+// 其他策略的实例被丢弃，下个tick重新创建。超时的蛇会写入录像，回放时跳过它们的决策。
+func (gs *GameState) decideAI() {
+	world := gs.newWorldView()
+	gs.world = world
+
+	var jobs []*aiJob
+	var timedOut []string
+	for i, snake := range gs.order {
+		if !snake.IsAI || snake.Dead {
+			continue
+		}
+		// 回放录像时，录制中超时的蛇同样不做决策
+		if gs.timedOut[snake.ID] {
+			if _, ok := snake.strategy.(StatefulStrategy); !ok {
+				snake.strategy = nil
+			}
+			timedOut = append(timedOut, snake.ID)
+			continue
+		}
+		if snake.strategy == nil {
+			strategy, err := NewStrategy(gs.config.AIStrategy)
+			if err != nil {
+				// 配置已经校验过策略名称，只有回放其他版本的录像时才会找不到策略
+				log.Printf("蛇 %s 使用性格策略代替: %v", snake.ID, err)
+				strategy = personalityStrategy{}
+			}
+			snake.strategy = strategy
+		}
+		jobs = append(jobs, &aiJob{snake: snake, strategy: gs.copyStrategy(snake), self: world.snakes[i]})
+	}
+	gs.timedOut = nil
+
+	workers := gs.config.AIWorkers
+	if workers == 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	runAIJobs(world, jobs, workers, time.Duration(gs.config.AIDeadline)*time.Millisecond)
+
+	for _, job := range jobs {
+		if !job.done {
+			if job.strategy == job.snake.strategy {
+				job.snake.strategy = nil
+			}
+			timedOut = append(timedOut, job.snake.ID)
+			continue
+		}
+		job.snake.strategy = job.strategy
+		if job.dir.Valid() && job.dir != job.snake.Direction.Opposite() {
+			job.snake.Direction = job.dir
+		}
+	}
+
+	for _, id := range timedOut {
+		gs.record(EventAITimeout, id, nil)
+	}
+	gs.metrics.timeouts += uint64(len(timedOut))
+	if len(timedOut) > 0 {
+		gs.metrics.lastTimedOut = timedOut
+	}
+	gs.logAITimeouts()
+}
+
+// copyStrategy 返回本tick用于决策的策略实例，调用方需持有锁
+// 有状态的策略通过序列化状态复制一份，复制失败时直接使用蛇的实例
+func (gs *GameState) copyStrategy(snake *Snake) Strategy {
+	stateful, ok := snake.strategy.(StatefulStrategy)
+	if !ok {
+		return snake.strategy
+	}
+	data, err := stateful.MarshalState()
+	if err == nil {
+		var copied Strategy
+		if copied, err = restoreStrategy(gs.config.AIStrategy, data); err == nil {
+			return copied
+		}
+	}
+	log.Printf("复制蛇 %s 的策略状态失败: %v", snake.ID, err)
+	return snake.strategy
+}
+
+// logAITimeouts 汇总输出上次输出以来的AI决策超时，第一次超时立即输出，之后最多每aiTimeoutLogInterval秒输出一次，调用方需持有锁
+func (gs *GameState) logAITimeouts() {
+	m := &gs.metrics
+	pending := m.timeouts - m.loggedTimeouts
+	if pending == 0 || m.timeoutLogTick > 0 && gs.tick < m.timeoutLogTick+gs.config.Ticks(aiTimeoutLogInterval) {
+		return
+	}
+	log.Printf("tick %d: 最近 %d 个tick中AI决策超时 %d 次，累计 %d 次，最近超时的蛇: %s",
+		gs.tick, gs.tick-m.timeoutLogTick, pending, m.timeouts, strings.Join(m.lastTimedOut, ", "))
+	m.loggedTimeouts = m.timeouts
+	m.timeoutLogTick = gs.tick
+}
+
+// decide 执行一条蛇的决策，策略panic时输出日志并返回false，与超时同样处理
+func decide(world *WorldView, job *aiJob) (dir Direction, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("蛇 %s 的AI策略panic: %v\n%s", job.snake.ID, r, debug.Stack())
+			ok = false
+		}
+	}()
+	return job.strategy.Decide(world, job.self), true
+}
+
+// runAIJobs 用workers个goroutine并发执行决策任务，deadline大于0时最多等待deadline
+// 返回时未完成或panic的任务done为false，之后完成的结果会被丢弃
+func runAIJobs(world *WorldView, jobs []*aiJob, workers int, deadline time.Duration) {
+	if len(jobs) == 0 {
+		return
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	var (
+		mu       sync.Mutex
+		next     int
+		expired  bool
+		pending  = len(jobs)
+		finished = make(chan struct{})
+	)
+	work := func() {
+		for {
+			mu.Lock()
+			if expired || next >= len(jobs) {
+				mu.Unlock()
+				return
+			}
+			job := jobs[next]
+			next++
+			mu.Unlock()
+
+			dir, ok := decide(world, job)
+
+			mu.Lock()
+			if !expired {
+				job.dir, job.done = dir, ok
+				pending--
+				if pending == 0 {
+					close(finished)
+				}
+			}
+			mu.Unlock()
+		}
+	}
+	for i := 0; i < workers; i++ {
+		go work()
+	}
+
+	var timeout <-chan time.Time
+	if deadline > 0 {
+		timer := time.NewTimer(deadline)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-finished:
+	case <-timeout:
+	}
+	mu.Lock()
+	expired = true
+	mu.Unlock()
+}
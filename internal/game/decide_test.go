@@ -0,0 +1,151 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countStrategy 记录决策次数的有状态策略，countRelease不为nil时决策前等待它关闭
+type countStrategy struct {
+	Count int `json:"count"`
+}
+
+var countRelease chan struct{}
+
+func init() {
+	RegisterStrategy("count", func() Strategy { return &countStrategy{} })
+}
+
+func (c *countStrategy) Decide(world *WorldView, self SnakeInfo) Direction {
+	if countRelease != nil {
+		<-countRelease
+	}
+	c.Count++
+	return self.Direction
+}
+
+func (c *countStrategy) MarshalState() ([]byte, error) { return json.Marshal(c) }
+
+func (c *countStrategy) UnmarshalState(data []byte) error { return json.Unmarshal(data, c) }
+
+// panicStrategy 每次决策都panic的策略
+type panicStrategy struct{}
+
+func init() {
+	RegisterStrategy("panic", func() Strategy { return panicStrategy{} })
+}
+
+func (panicStrategy) Decide(world *WorldView, self SnakeInfo) Direction {
+	panic("决策失败")
+}
+
+func TestStrategyPanic(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	config := DefaultConfig()
+	config.AIStrategy = "panic"
+	// 不限制决策时间，panic的任务仍然要结束本tick的等待
+	config.AIDeadline = 0
+	gs := newTestState(t, config)
+	snake := placeSnake(gs, "ai", Position{X: 10, Y: 10}, right, 3)
+
+	gs.UpdateGame()
+	if snake.Dead || snake.Direction != right {
+		t.Fatalf("策略panic后蛇的方向为 %v、死亡 %v，期望保持 %v", snake.Direction, snake.Dead, right)
+	}
+	if m := gs.Metrics(); m.AITimeouts != 1 {
+		t.Fatalf("AITimeouts = %d，期望 1", m.AITimeouts)
+	}
+	if !strings.Contains(buf.String(), "蛇 ai 的AI策略panic: 决策失败") {
+		t.Fatalf("日志中没有panic信息:\n%s", buf.String())
+	}
+}
+
+func TestStatefulStrategy(t *testing.T) {
+	config := DefaultConfig()
+	config.AIStrategy = "count"
+	config.AIDeadline = 50
+	gs := newTestState(t, config)
+	snake := placeSnake(gs, "ai", Position{X: 10, Y: 10}, right, 3)
+	count := func(snake *Snake) int {
+		t.Helper()
+		strategy, ok := snake.strategy.(*countStrategy)
+		if !ok {
+			t.Fatalf("蛇的策略为 %T，期望 *countStrategy", snake.strategy)
+		}
+		return strategy.Count
+	}
+
+	for i := 0; i < 3; i++ {
+		gs.decideAI()
+	}
+	if got := count(snake); got != 3 {
+		t.Fatalf("3个tick后决策了 %d 次，期望 3", got)
+	}
+
+	// 超时的决策不影响蛇的策略状态
+	countRelease = make(chan struct{})
+	start := time.Now()
+	gs.decideAI()
+	close(countRelease)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("超时的决策等待了 %v", elapsed)
+	}
+	if m := gs.Metrics(); m.AITimeouts != 1 {
+		t.Fatalf("AITimeouts = %d，期望 1", m.AITimeouts)
+	}
+	if got := count(snake); got != 3 {
+		t.Fatalf("超时后决策次数为 %d，期望保留 3", got)
+	}
+	gs.decideAI()
+	if got := count(snake); got != 4 {
+		t.Fatalf("超时后的下个tick决策次数为 %d，期望 4", got)
+	}
+
+	// 快照保存策略状态，恢复后的蛇继续使用它
+	restored := restoreGameState(config, gs.Snapshot())
+	if got := count(restored.snakes["ai"]); got != 4 {
+		t.Fatalf("从快照恢复的决策次数为 %d，期望 4", got)
+	}
+}
+
+func TestAITimeoutLogRateLimited(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	config := DefaultConfig()
+	config.UpdateInterval = 100
+	gs := newTestState(t, config)
+	// 前250个tick每个tick都有一条蛇超时，之后不再超时
+	for tick := uint64(1); tick <= 400; tick++ {
+		gs.tick = tick
+		if tick <= 250 {
+			gs.metrics.timeouts++
+			gs.metrics.lastTimedOut = []string{"s1"}
+		}
+		gs.logAITimeouts()
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("输出了 %d 行日志，期望 4 行:\n%s", len(lines), buf.String())
+	}
+	// 第一次超时立即输出，之后每100个tick汇总一次，不再超时后输出剩余的次数
+	want := []string{"超时 1 次，累计 1 次", "超时 100 次，累计 101 次", "超时 100 次，累计 201 次", "超时 49 次，累计 250 次"}
+	for i, want := range want {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("第 %d 行日志为 %q，期望包含 %q", i+1, lines[i], want)
+		}
+	}
+	if m := gs.Metrics(); m.AITimeouts != 250 {
+		t.Fatalf("AITimeouts = %d，期望 250", m.AITimeouts)
+	}
+}
@@ -0,0 +1,186 @@
+package game
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ASCII地图中的字符
+const (
+	TileEmpty = '.' // 空地，空格也视为空地
+	TileWall  = '#' // 墙
+	TileSpawn = 'S' // 出生区域
+	TileApple = 'A' // 苹果生成区域
+)
+
+// ErrEmptyMap 地图中没有可以通行的格子
+var ErrEmptyMap = errors.New("地图中没有可以通行的格子")
+
+// GameMap 静态地图，定义墙、出生区域和苹果生成区域
+//
+// 没有出生区域时蛇在任意空地出生，没有苹果生成区域时苹果在任意空地生成。
+// JSON格式的地图文件与推送给客户端的格式相同。
+type GameMap struct {
+	Cols   int        `json:"cols"`
+	Rows   int        `json:"rows"`
+	Walls  []Position `json:"walls"`
+	Spawns []Position `json:"spawns,omitempty"`
+	Apples []Position `json:"apples,omitempty"`
+
+	walls map[Position]bool
+	open  []Position // 所有空地，按行排列
+}
+
+// LoadMap 读取地图文件，扩展名为.json时按JSON解析，否则按ASCII解析
+func LoadMap(path string) (*GameMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取地图文件失败: %w", err)
+	}
+	var m *GameMap
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		m = &GameMap{}
+		err = json.Unmarshal(data, m)
+	} else {
+		m, err = ParseASCIIMap(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析地图文件 %s 失败: %w", filepath.Base(path), err)
+	}
+	if err := m.init(); err != nil {
+		return nil, fmt.Errorf("地图文件 %s 无效: %w", filepath.Base(path), err)
+	}
+	return m, nil
+}
+
+// ParseASCIIMap 解析ASCII地图，每行是地图的一行，行数和最长一行的长度决定地图尺寸
+// 较短的行右侧补空地，以 ; 开头的行是注释
+func ParseASCIIMap(data []byte) (*GameMap, error) {
+	m := &GameMap{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	y := 0
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, ";") {
+			continue
+		}
+		for x, tile := range []byte(line) {
+			pos := Position{X: x, Y: y}
+			switch tile {
+			case TileEmpty, ' ':
+			case TileWall:
+				m.Walls = append(m.Walls, pos)
+			case TileSpawn:
+				m.Spawns = append(m.Spawns, pos)
+			case TileApple:
+				m.Apples = append(m.Apples, pos)
+			default:
+				return nil, fmt.Errorf("第%d行第%d列: 未知的字符 %q", y+1, x+1, tile)
+			}
+		}
+		if len(line) > m.Cols {
+			m.Cols = len(line)
+		}
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	m.Rows = y
+	return m, nil
+}
+
+// init 检查地图内容并建立墙的索引
+func (m *GameMap) init() error {
+	if m.Cols <= 0 || m.Rows <= 0 {
+		return fmt.Errorf("地图尺寸必须大于0，当前为 %dx%d", m.Cols, m.Rows)
+	}
+	m.walls = make(map[Position]bool, len(m.Walls))
+	for _, pos := range m.Walls {
+		if !m.contains(pos) {
+			return fmt.Errorf("墙 (%d,%d) 超出地图范围", pos.X, pos.Y)
+		}
+		m.walls[pos] = true
+	}
+	m.open = m.open[:0]
+	for y := 0; y < m.Rows; y++ {
+		for x := 0; x < m.Cols; x++ {
+			if pos := (Position{X: x, Y: y}); !m.walls[pos] {
+				m.open = append(m.open, pos)
+			}
+		}
+	}
+	if len(m.open) == 0 {
+		return ErrEmptyMap
+	}
+	for _, zone := range [][]Position{m.Spawns, m.Apples} {
+		for _, pos := range zone {
+			if !m.contains(pos) || m.walls[pos] {
+				return fmt.Errorf("出生或苹果区域 (%d,%d) 不在地图的空地上", pos.X, pos.Y)
+			}
+		}
+	}
+	return nil
+}
+
+// contains 判断位置是否在地图范围内
+func (m *GameMap) contains(pos Position) bool {
+	return pos.X >= 0 && pos.X < m.Cols && pos.Y >= 0 && pos.Y < m.Rows
+}
+
+// LoadMap 加载MapFile指定的地图，地图的尺寸覆盖Cols和Rows，MapFile为空时不加载
+func (c *GameConfig) LoadMap() error {
+	if c.MapFile == "" {
+		c.Map = nil
+		return nil
+	}
+	m, err := LoadMap(c.MapFile)
+	if err != nil {
+		return err
+	}
+	c.Map = m
+	c.Cols, c.Rows = m.Cols, m.Rows
+	return nil
+}
+
+// spawnCells 返回可以出生的格子数量：有出生区域时为其中不同格子的数量，否则为空地数量
+func (m *GameMap) spawnCells() int {
+	if len(m.Spawns) == 0 {
+		return len(m.open)
+	}
+	cells := make(map[Position]bool, len(m.Spawns))
+	for _, pos := range m.Spawns {
+		cells[pos] = true
+	}
+	return len(cells)
+}
+
+// wall 判断位置是否是地图中的墙
+func (c *GameConfig) wall(pos Position) bool {
+	return c.Map != nil && c.Map.walls[pos]
+}
+
+// maxOpenAttempts randomOpen在整个场地上随机选择空地的最多次数
+const maxOpenAttempts = 64
+
+// randomOpen 返回一个随机的空地，zone不为空时从zone中选择，调用方需持有锁
+// 连续maxOpenAttempts次选中墙时改为从所有空地中选择，墙很多的地图上也不会一直重试
+func (gs *GameState) randomOpen(zone []Position) Position {
+	if len(zone) > 0 {
+		return zone[gs.rng.Intn(len(zone))]
+	}
+	for i := 0; i < maxOpenAttempts; i++ {
+		pos := Position{X: gs.rng.Intn(gs.config.Cols), Y: gs.rng.Intn(gs.config.Rows)}
+		if !gs.config.wall(pos) {
+			return pos
+		}
+	}
+	open := gs.config.Map.open
+	return open[gs.rng.Intn(len(open))]
+}
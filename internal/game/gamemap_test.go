@@ -0,0 +1,87 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mapConfig 使用ASCII地图创建配置
+func mapConfig(t *testing.T, rows ...string) *GameConfig {
+	t.Helper()
+	m, err := ParseASCIIMap([]byte(strings.Join(rows, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.init(); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.Map = m
+	config.MapFile = "test"
+	config.Cols, config.Rows = m.Cols, m.Rows
+	config.InitialSnakeLength = 3
+	config.Seed = 1
+	return config
+}
+
+func TestValidateSpawnCells(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    []string
+		count   int
+		wantErr bool
+	}{
+		{"出生区域足够", []string{"S........", "........S"}, 2, false},
+		{"出生区域不足", []string{"S........", "........."}, 2, true},
+		{"每个出生格子容纳一条蛇", []string{"S........", "........."}, 1, false},
+		{"没有出生区域时按空地计算", []string{"##########", "#........#", "##########"}, 9, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := mapConfig(t, tt.rows...)
+			config.InitialAICount, config.MaxAICount = tt.count, tt.count
+			config.InitialSnakeLength = 1
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v，期望出错: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewGameStateNoSpawn(t *testing.T) {
+	config := mapConfig(t, "S.........", "..........")
+	config.InitialAICount, config.MaxAICount = 2, 2
+	if _, err := NewGameState(config); !errors.Is(err, ErrNoSpawn) {
+		t.Fatalf("NewGameState() = %v，期望 %v", err, ErrNoSpawn)
+	}
+}
+
+func TestRandomOpenMostlyWalls(t *testing.T) {
+	rows := make([]string, 20)
+	for i := range rows {
+		rows[i] = strings.Repeat("#", 20)
+	}
+	rows[13] = strings.Repeat("#", 7) + "." + strings.Repeat("#", 12)
+	config := mapConfig(t, rows...)
+	config.InitialAICount, config.MaxAICount = 0, 0
+	gs, err := NewGameState(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if pos := gs.randomOpen(nil); pos != (Position{X: 7, Y: 13}) {
+			t.Fatalf("randomOpen() = %v，期望唯一的空地 (7,13)", pos)
+		}
+	}
+}
+
+func TestEmptyMap(t *testing.T) {
+	m, err := ParseASCIIMap([]byte("###\n###"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.init(); !errors.Is(err, ErrEmptyMap) {
+		t.Fatalf("init() = %v，期望 %v", err, ErrEmptyMap)
+	}
+}
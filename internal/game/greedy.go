@@ -0,0 +1,74 @@
+package game
+
+import "encoding/json"
+
+// StrategyGreedy 追逐视野内最近的苹果的简单策略
+const StrategyGreedy = "greedy"
+
+// greedyStrategy 记住正在追逐的苹果，直到吃到或苹果离开视野才选择新的目标
+// 目标通过MarshalState写入快照，从快照回放时与录制时一致
+type greedyStrategy struct {
+	Target *Position `json:"target,omitempty"`
+}
+
+func init() {
+	RegisterStrategy(StrategyGreedy, func() Strategy { return &greedyStrategy{} })
+}
+
+func (g *greedyStrategy) Decide(world *WorldView, self SnakeInfo) Direction {
+	head := Position{X: self.X, Y: self.Y}
+	view := world.View(self)
+
+	if g.Target != nil && !containsPosition(view.Food, *g.Target) {
+		g.Target = nil
+	}
+	if g.Target == nil {
+		// 距离相同时选择视野中先出现的苹果
+		nearest := -1
+		for _, food := range view.Food {
+			if d := world.Distance(head, food); nearest < 0 || d < nearest {
+				target := food
+				g.Target, nearest = &target, d
+			}
+		}
+	}
+
+	// 先考虑当前方向，距离相同时不转向；所有方向都会撞上时保持当前方向
+	dirs := []Direction{self.Direction, {0, -1}, {0, 1}, {-1, 0}, {1, 0}}
+	choice, best := self.Direction, -1
+	for _, dir := range dirs {
+		if dir == self.Direction.Opposite() {
+			continue
+		}
+		next, ok := world.Step(head, dir)
+		if !ok || world.Occupied(next) {
+			continue
+		}
+		d := 0
+		if g.Target != nil {
+			d = world.Distance(next, *g.Target)
+		}
+		if best < 0 || d < best {
+			choice, best = dir, d
+		}
+	}
+	return choice
+}
+
+func (g *greedyStrategy) MarshalState() ([]byte, error) {
+	return json.Marshal(g)
+}
+
+func (g *greedyStrategy) UnmarshalState(data []byte) error {
+	return json.Unmarshal(data, g)
+}
+
+// containsPosition 判断列表中是否包含指定位置
+func containsPosition(positions []Position, pos Position) bool {
+	for _, p := range positions {
+		if p == pos {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package game
+
+// grid 以格子为索引记录蛇和苹果的位置，随移动、生长和死亡增量更新
+//
+// 碰撞、出生位置、苹果生成和AI的障碍物检查都通过它在常数时间内完成，
+// 不再遍历所有蛇的身体。移动后、处理碰撞前同一格可能有多条蛇，因此每格保存一个列表。
+type grid struct {
+	cols   int
+	bodies [][]*Snake // 每格上身体所在的蛇，身体重叠时同一条蛇出现多次
+	heads  [][]*Snake // 每格上蛇头所在的蛇
+	apples []int      // 每格上的苹果数量
+}
+
+// newGrid 创建指定大小的空网格
+func newGrid(cols, rows int) *grid {
+	return &grid{
+		cols:   cols,
+		bodies: make([][]*Snake, cols*rows),
+		heads:  make([][]*Snake, cols*rows),
+		apples: make([]int, cols*rows),
+	}
+}
+
+// index 返回位置在网格中的下标
+func (g *grid) index(pos Position) int {
+	return pos.Y*g.cols + pos.X
+}
+
+// addSnake 记录一条蛇的头和身体
+func (g *grid) addSnake(snake *Snake) {
+	g.addHead(Position{X: snake.X, Y: snake.Y}, snake)
+	for _, segment := range snake.Body {
+		g.addBody(segment, snake)
+	}
+}
+
+// removeSnake 清除一条蛇的头和身体
+func (g *grid) removeSnake(snake *Snake) {
+	g.removeHead(Position{X: snake.X, Y: snake.Y}, snake)
+	for _, segment := range snake.Body {
+		g.removeBody(segment, snake)
+	}
+}
+
+func (g *grid) addHead(pos Position, snake *Snake) {
+	i := g.index(pos)
+	g.heads[i] = append(g.heads[i], snake)
+}
+
+func (g *grid) removeHead(pos Position, snake *Snake) {
+	i := g.index(pos)
+	g.heads[i] = without(g.heads[i], snake)
+}
+
+func (g *grid) addBody(pos Position, snake *Snake) {
+	i := g.index(pos)
+	g.bodies[i] = append(g.bodies[i], snake)
+}
+
+func (g *grid) removeBody(pos Position, snake *Snake) {
+	i := g.index(pos)
+	g.bodies[i] = without(g.bodies[i], snake)
+}
+
+// occupied 判断格子上是否有任何蛇的头或身体
+func (g *grid) occupied(pos Position) bool {
+	i := g.index(pos)
+	return len(g.bodies[i]) > 0 || len(g.heads[i]) > 0
+}
+
+// bodiesAt 返回身体位于该格的蛇，返回的切片不能修改
+func (g *grid) bodiesAt(pos Position) []*Snake {
+	return g.bodies[g.index(pos)]
+}
+
+// headsAt 返回蛇头位于该格的蛇，返回的切片不能修改
+func (g *grid) headsAt(pos Position) []*Snake {
+	return g.heads[g.index(pos)]
+}
+
+func (g *grid) addApple(pos Position) {
+	g.apples[g.index(pos)]++
+}
+
+func (g *grid) removeApple(pos Position) {
+	g.apples[g.index(pos)]--
+}
+
+// hasApple 判断格子上是否有苹果
+func (g *grid) hasApple(pos Position) bool {
+	return g.apples[g.index(pos)] > 0
+}
+
+// without 从列表中移除一个元素，保留切片容量以便之后复用
+func without(snakes []*Snake, snake *Snake) []*Snake {
+	for i, s := range snakes {
+		if s == snake {
+			last := len(snakes) - 1
+			snakes[i] = snakes[last]
+			snakes[last] = nil
+			return snakes[:last]
+		}
+	}
+	return snakes
+}
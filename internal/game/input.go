@@ -0,0 +1,64 @@
+package game
+
+import "errors"
+
+// InputBufferSize 每个玩家最多缓存的转向输入数，连续快速按键时每个tick应用一个
+const InputBufferSize = 3
+
+var (
+	// ErrInvalidDirection 方向不是上下左右四个单位向量之一
+	ErrInvalidDirection = errors.New("方向必须是上下左右之一")
+	// ErrReverseDirection 方向与蛇当前的移动方向相反
+	ErrReverseDirection = errors.New("不能直接向反方向移动")
+)
+
+// Valid 判断方向是否为上下左右四个单位向量之一
+func (d Direction) Valid() bool {
+	return d.X*d.X+d.Y*d.Y == 1
+}
+
+// Opposite 返回相反的方向
+func (d Direction) Opposite() Direction {
+	return Direction{-d.X, -d.Y}
+}
+
+// lastInput 返回缓存中最后一个输入，没有缓存时返回上一次实际移动的方向
+func (s *Snake) lastInput() Direction {
+	if n := len(s.Inputs); n > 0 {
+		return s.Inputs[n-1]
+	}
+	return s.Direction
+}
+
+// queueInput 检查转向输入并放入缓存，调用方需持有锁
+// 与前一个输入相同或缓存已满的输入被忽略，返回false
+func (s *Snake) queueInput(dir Direction) (bool, error) {
+	if !dir.Valid() {
+		return false, ErrInvalidDirection
+	}
+	last := s.lastInput()
+	if dir == last.Opposite() {
+		return false, ErrReverseDirection
+	}
+	if dir == last || len(s.Inputs) >= InputBufferSize {
+		return false, nil
+	}
+	s.Inputs = append(s.Inputs, dir)
+	return true, nil
+}
+
+// applyInput 取出缓存中的第一个输入作为本tick的移动方向，调用方需持有锁
+func (s *Snake) applyInput() {
+	for len(s.Inputs) > 0 {
+		dir := s.Inputs[0]
+		s.Inputs = s.Inputs[1:]
+		// 缓存的输入已按顺序检查过，这里再以实际移动的方向为准检查一次
+		if dir != s.Direction.Opposite() {
+			s.Direction = dir
+			break
+		}
+	}
+	if len(s.Inputs) == 0 {
+		s.Inputs = nil
+	}
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	up    = Direction{X: 0, Y: -1}
+	down  = Direction{X: 0, Y: 1}
+	left  = Direction{X: -1, Y: 0}
+	right = Direction{X: 1, Y: 0}
+)
+
+func TestQueueInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		inputs  []Direction // 已缓存的输入，蛇当前向右移动
+		dir     Direction
+		queued  bool
+		wantErr error
+		want    []Direction
+	}{
+		{"转向", nil, up, true, nil, []Direction{up}},
+		{"与当前方向相同", nil, right, false, nil, nil},
+		{"与当前方向相反", nil, left, false, ErrReverseDirection, nil},
+		{"零向量", nil, Direction{}, false, ErrInvalidDirection, nil},
+		{"斜向", nil, Direction{X: 1, Y: 1}, false, ErrInvalidDirection, nil},
+		{"步长超过一格", nil, Direction{X: 0, Y: 2}, false, ErrInvalidDirection, nil},
+		{"与上一个输入相同", []Direction{up}, up, false, nil, []Direction{up}},
+		{"与上一个输入相反", []Direction{up}, down, false, ErrReverseDirection, []Direction{up}},
+		{"按上一个输入判断反向", []Direction{up}, left, true, nil, []Direction{up, left}},
+		{"缓存已满", []Direction{up, left, down}, right, false, nil, []Direction{up, left, down}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snake := &Snake{Direction: right, Inputs: append([]Direction(nil), tt.inputs...)}
+			queued, err := snake.queueInput(tt.dir)
+			if queued != tt.queued || err != tt.wantErr {
+				t.Fatalf("queueInput(%v) = %v, %v，期望 %v, %v", tt.dir, queued, err, tt.queued, tt.wantErr)
+			}
+			if !reflect.DeepEqual(snake.Inputs, tt.want) {
+				t.Fatalf("缓存为 %v，期望 %v", snake.Inputs, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputBufferSize(t *testing.T) {
+	snake := &Snake{Direction: right}
+	// 交替按上和左，每个输入都合法，超过缓存大小的被忽略
+	for i := 0; i < InputBufferSize+3; i++ {
+		dir := up
+		if i%2 == 1 {
+			dir = left
+		}
+		queued, err := snake.queueInput(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < InputBufferSize; queued != want {
+			t.Fatalf("第 %d 个输入是否缓存为 %v，期望 %v", i+1, queued, want)
+		}
+	}
+	if len(snake.Inputs) != InputBufferSize {
+		t.Fatalf("缓存了 %d 个输入，期望 %d", len(snake.Inputs), InputBufferSize)
+	}
+}
+
+func TestApplyInputOnePerTick(t *testing.T) {
+	config := DefaultConfig()
+	config.Cols, config.Rows = 20, 20
+	gs := newTestState(t, config)
+	snake := placeSnake(gs, "p", Position{X: 10, Y: 10}, right, 3)
+	snake.IsAI = false
+
+	for _, dir := range []Direction{up, left, down} {
+		if err := gs.UpdateSnakeDirection(snake.ID, dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gs.UpdateSnakeDirection(snake.ID, up); err != ErrReverseDirection {
+		t.Fatalf("与最后一个输入相反时返回 %v，期望 %v", err, ErrReverseDirection)
+	}
+	// 每个tick只应用一个输入
+	for i, want := range []Direction{up, left, down, down} {
+		gs.moveSnakes()
+		if snake.Direction != want {
+			t.Fatalf("第 %d 个tick的方向为 %v，期望 %v", i+1, snake.Direction, want)
+		}
+	}
+	if snake.Inputs != nil {
+		t.Fatalf("输入应用完后缓存为 %v，期望为空", snake.Inputs)
+	}
+}
+
+func TestApplyInputSkipsReverse(t *testing.T) {
+	// 缓存中与实际移动方向相反的输入被跳过，应用下一个输入
+	snake := &Snake{Direction: right, Inputs: []Direction{left, up}}
+	snake.applyInput()
+	if snake.Direction != up || len(snake.Inputs) != 0 {
+		t.Fatalf("方向为 %v、剩余输入 %v，期望 %v 且没有剩余输入", snake.Direction, snake.Inputs, up)
+	}
+	snake = &Snake{Direction: right, Inputs: []Direction{left}}
+	snake.applyInput()
+	if snake.Direction != right {
+		t.Fatalf("方向为 %v，期望保持 %v", snake.Direction, right)
+	}
+}
@@ -0,0 +1,74 @@
+package game
+
+import "time"
+
+// TickMetrics 游戏循环的耗时统计，时间单位为毫秒
+type TickMetrics struct {
+	Ticks    uint64  `json:"ticks"`    // 统计的tick数
+	LastMs   float64 `json:"lastMs"`   // 最近一个tick的耗时
+	AvgMs    float64 `json:"avgMs"`    // 每个tick的平均耗时
+	MaxMs    float64 `json:"maxMs"`    // 单个tick的最长耗时
+	AILastMs float64 `json:"aiLastMs"` // 最近一个tick中AI决策的耗时
+	AIAvgMs  float64 `json:"aiAvgMs"`  // 每个tick中AI决策的平均耗时
+	// Overruns 耗时超过update_interval的tick数
+	Overruns uint64 `json:"overruns"`
+	// AITimeouts 超过ai_deadline未完成决策的累计次数
+	AITimeouts uint64 `json:"aiTimeouts"`
+}
+
+// tickMetrics 游戏状态内部累计的耗时
+type tickMetrics struct {
+	ticks    uint64
+	total    time.Duration
+	max      time.Duration
+	last     time.Duration
+	aiTotal  time.Duration
+	aiLast   time.Duration
+	overruns uint64
+	timeouts uint64
+
+	// 超时日志的汇总状态
+	loggedTimeouts uint64   // 上次输出日志时的累计超时次数
+	timeoutLogTick uint64   // 上次输出日志的tick
+	lastTimedOut   []string // 最近一个有超时的tick中超时的蛇
+}
+
+// add 记录一个tick的总耗时和AI决策耗时
+func (m *tickMetrics) add(elapsed, ai, interval time.Duration) {
+	m.ticks++
+	m.total += elapsed
+	m.last = elapsed
+	if elapsed > m.max {
+		m.max = elapsed
+	}
+	m.aiTotal += ai
+	m.aiLast = ai
+	if elapsed > interval {
+		m.overruns++
+	}
+}
+
+// Metrics 返回游戏循环的耗时统计
+func (gs *GameState) Metrics() TickMetrics {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	m := gs.metrics
+	out := TickMetrics{
+		Ticks:      m.ticks,
+		LastMs:     milliseconds(m.last),
+		MaxMs:      milliseconds(m.max),
+		AILastMs:   milliseconds(m.aiLast),
+		Overruns:   m.overruns,
+		AITimeouts: m.timeouts,
+	}
+	if m.ticks > 0 {
+		out.AvgMs = milliseconds(m.total / time.Duration(m.ticks))
+		out.AIAvgMs = milliseconds(m.aiTotal / time.Duration(m.ticks))
+	}
+	return out
+}
+
+// milliseconds 将时长换算为毫秒
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,220 @@
+package game
+
+// HeadOnRule 两条蛇的头同时进入同一格时的处理规则
+type HeadOnRule string
+
+const (
+	// HeadOnBoth 相撞的蛇全部死亡
+	HeadOnBoth HeadOnRule = "both"
+	// HeadOnLonger 最长的蛇存活并获得击杀，长度相同时全部死亡
+	HeadOnLonger HeadOnRule = "longer"
+)
+
+// moveSnakes 让所有存活且未冻结的蛇同时前进一格，然后按移动后的状态处理碰撞，调用方需持有锁
+//
+// 蛇头进入任何蛇的身体(包括自己)或冻结的蛇的头部时死亡，身体的主人获得击杀；
+// 地图边缘为墙时，将要离开地图的蛇停在原地并死亡，没有击杀者；
+// 多条蛇的头进入同一格，或两条相邻的蛇相向移动交换位置时，按配置的HeadOnRule处理。所有碰撞都基于移动后的同一个状态判断，
+// 结果与蛇的处理顺序无关：蛇头进入有苹果的格子时保留尾部，但比较长度时使用吃苹果之前的长度；
+// 只有存活的蛇吃掉苹果并变长，死亡的蛇去掉保留的尾部，苹果留在原地。
+func (gs *GameState) moveSnakes() {
+	moved := make([]*Snake, 0, len(gs.order))
+	var walled []*Snake
+	eating := make(map[*Snake]bool)
+	from := make(map[Position]*Snake) // 移动前的蛇头位置
+	for _, snake := range gs.order {
+		// 断线等待重连的蛇保持静止
+		if snake.Dead || snake.Frozen {
+			continue
+		}
+		// 每个tick最多应用一次玩家的转向
+		snake.applyInput()
+
+		// 撞墙的蛇停在原地，在碰撞处理后死亡
+		next, ok := gs.config.step(snake.X, snake.Y, snake.Direction)
+		if !ok {
+			walled = append(walled, snake)
+			continue
+		}
+
+		// 旧的头部成为身体第一节
+		head := Position{X: snake.X, Y: snake.Y}
+		from[head] = snake
+		snake.Body = append([]Position{head}, snake.Body...)
+		gs.grid.addBody(head, snake)
+		gs.grid.removeHead(head, snake)
+		snake.X, snake.Y = next.X, next.Y
+		gs.grid.addHead(next, snake)
+
+		// 蛇头进入有苹果的格子时保留尾部，否则去掉尾部；苹果在碰撞处理后才被吃掉
+		if gs.grid.hasApple(next) {
+			eating[snake] = true
+		} else if n := len(snake.Body); n > 0 {
+			gs.grid.removeBody(snake.Body[n-1], snake)
+			snake.Body = snake.Body[:n-1]
+		}
+		moved = append(moved, snake)
+	}
+
+	// 冻结和撞墙的蛇头部不动，同样视为障碍
+	stopped := make(map[*Snake]bool, len(walled))
+	for _, snake := range walled {
+		stopped[snake] = true
+	}
+	obstacle := func(snake *Snake) bool {
+		return snake.Frozen || stopped[snake]
+	}
+
+	// 两条相邻的蛇相向移动时各自进入对方原来的头部，视为头对头而不是撞上身体
+	swapped := make(map[*Snake]*Snake)
+	for _, snake := range moved {
+		other := from[Position{X: snake.X, Y: snake.Y}]
+		if other != nil && other != snake && len(snake.Body) > 0 && (Position{X: other.X, Y: other.Y}) == snake.Body[0] {
+			swapped[snake] = other
+		}
+	}
+
+	dead := make(map[*Snake]bool)
+	killers := make(map[*Snake]*Snake)
+	// headOn 按HeadOnRule处理头对头的一组蛇
+	headOn := func(group []*Snake) {
+		winner := gs.headOnWinner(group, eating)
+		for _, other := range group {
+			if other == winner {
+				continue
+			}
+			if !dead[other] {
+				dead[other] = true
+				if winner != nil {
+					killers[other] = winner
+				}
+			}
+		}
+	}
+	resolved := make(map[Position]bool)
+	for _, snake := range moved {
+		head := Position{X: snake.X, Y: snake.Y}
+
+		// 撞上身体：把击杀记给创建最早的其他蛇，只撞到自己时没有击杀者
+		hit := false
+		var killer *Snake
+		credit := func(owner *Snake) {
+			hit = true
+			if owner != snake {
+				killer = earlier(killer, owner)
+			}
+		}
+		for _, owner := range gs.grid.bodiesAt(head) {
+			// 交换位置的对方的身体第一节在头对头中处理
+			if owner != swapped[snake] {
+				credit(owner)
+			}
+		}
+		for _, other := range gs.grid.headsAt(head) {
+			if obstacle(other) {
+				credit(other)
+			}
+		}
+		if hit {
+			dead[snake] = true
+			if killer != nil {
+				killers[snake] = killer
+			}
+		}
+
+		// 交换位置的两条蛇由加入较早的一条处理
+		if partner := swapped[snake]; partner != nil && snake.rank < partner.rank {
+			headOn([]*Snake{snake, partner})
+		}
+
+		// 头对头：每个格子只处理一次
+		if resolved[head] {
+			continue
+		}
+		resolved[head] = true
+		var group []*Snake
+		for _, other := range gs.grid.headsAt(head) {
+			if !obstacle(other) {
+				group = append(group, other)
+			}
+		}
+		if len(group) >= 2 {
+			headOn(group)
+		}
+	}
+
+	// 存活的蛇吃掉苹果，死亡的蛇去掉保留的尾部；
+	// 在死亡的蛇转换为苹果之前处理，存活的蛇不会吃到同一tick中留下的苹果
+	for _, snake := range moved {
+		if !eating[snake] {
+			continue
+		}
+		if !dead[snake] && gs.eatAppleAt(Position{X: snake.X, Y: snake.Y}) {
+			snake.eatApple()
+			snake.updateMaxLength()
+		} else if n := len(snake.Body); n > 0 {
+			gs.grid.removeBody(snake.Body[n-1], snake)
+			snake.Body = snake.Body[:n-1]
+		}
+	}
+
+	for _, snake := range moved {
+		if dead[snake] {
+			gs.snakeToApples(snake, killers[snake])
+		}
+	}
+	for _, snake := range walled {
+		gs.snakeToApples(snake, nil)
+	}
+}
+
+// earlier 返回两条蛇中加入游戏较早的一条，a为nil时返回b
+func earlier(a, b *Snake) *Snake {
+	if a == nil || b.rank < a.rank {
+		return b
+	}
+	return a
+}
+
+// headOnWinner 返回头对头相撞的一组蛇中存活的蛇，没有时返回nil，调用方需持有锁
+// eating中的蛇本tick保留了尾部，按吃苹果之前的长度比较
+func (gs *GameState) headOnWinner(group []*Snake, eating map[*Snake]bool) *Snake {
+	if gs.config.HeadOnRule != HeadOnLonger {
+		return nil
+	}
+	length := func(snake *Snake) int {
+		if eating[snake] {
+			return len(snake.Body) - 1
+		}
+		return len(snake.Body)
+	}
+	var winner *Snake
+	tie := false
+	for _, snake := range group {
+		switch {
+		case winner == nil || length(snake) > length(winner):
+			winner, tie = snake, false
+		case length(snake) == length(winner):
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return winner
+}
+
+// eatAppleAt 移除指定位置的苹果，返回该位置是否有苹果，调用方需持有锁
+func (gs *GameState) eatAppleAt(pos Position) bool {
+	if !gs.grid.hasApple(pos) {
+		return false
+	}
+	gs.grid.removeApple(pos)
+	for i, apple := range gs.apples {
+		if apple.Position == pos {
+			gs.apples = append(gs.apples[:i], gs.apples[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
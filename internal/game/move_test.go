@@ -0,0 +1,136 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestState 创建没有AI蛇和苹果的空场地
+func newTestState(t *testing.T, config *GameConfig) *GameState {
+	t.Helper()
+	config.InitialAICount, config.MaxAICount = 0, 0
+	config.Seed = 1
+	gs, err := NewGameState(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gs
+}
+
+// placeSnake 在场地上放一条蛇，身体从蛇头向dir的反方向铺设length节
+func placeSnake(gs *GameState, id string, head Position, dir Direction, length int) *Snake {
+	snake := &Snake{ID: id, Name: id, IsAI: true, X: head.X, Y: head.Y, Direction: dir}
+	for i := 1; i <= length; i++ {
+		snake.Body = append(snake.Body, gs.config.normalize(Position{X: head.X - dir.X*i, Y: head.Y - dir.Y*i}))
+	}
+	snake.MaxLength = len(snake.Body)
+	gs.addSnake(snake)
+	return snake
+}
+
+func TestHeadOn(t *testing.T) {
+	meet := Position{X: 10, Y: 5}
+	tests := []struct {
+		name       string
+		rule       HeadOnRule
+		lenA, lenB int
+		apple      bool
+		swap       bool // 两条蛇相邻，相向移动后交换位置
+		alive      []string
+		length     int // 存活的蛇移动后的身体长度
+		apples     int // 相撞格子上剩下的苹果数
+	}{
+		{"both规则", HeadOnBoth, 4, 4, false, false, nil, 0, 0},
+		{"both规则有苹果", HeadOnBoth, 4, 4, true, false, nil, 0, 1},
+		{"both规则长度不同", HeadOnBoth, 6, 4, true, false, nil, 0, 1},
+		{"longer规则长度相同", HeadOnLonger, 4, 4, false, false, nil, 0, 0},
+		{"longer规则长度相同有苹果", HeadOnLonger, 4, 4, true, false, nil, 0, 1},
+		{"longer规则先创建的较长", HeadOnLonger, 6, 4, false, false, []string{"a"}, 6, 0},
+		{"longer规则后创建的较长", HeadOnLonger, 4, 6, false, false, []string{"b"}, 6, 0},
+		{"longer规则较长的吃到苹果", HeadOnLonger, 4, 6, true, false, []string{"b"}, 7, 0},
+		{"longer规则短一节的不因苹果获胜", HeadOnLonger, 5, 6, true, false, []string{"b"}, 7, 0},
+		{"both规则交换位置", HeadOnBoth, 8, 3, false, true, nil, 0, 0},
+		{"longer规则交换位置长度相同", HeadOnLonger, 4, 4, false, true, nil, 0, 0},
+		{"longer规则交换位置先创建的较长", HeadOnLonger, 8, 3, false, true, []string{"a"}, 8, 0},
+		{"longer规则交换位置后创建的较长", HeadOnLonger, 3, 8, false, true, []string{"b"}, 8, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Cols, config.Rows = 20, 10
+			config.HeadOnRule = tt.rule
+			gs := newTestState(t, config)
+			headB := Position{X: meet.X + 1, Y: meet.Y}
+			if tt.swap {
+				headB = meet
+			}
+			a := placeSnake(gs, "a", Position{X: meet.X - 1, Y: meet.Y}, Direction{X: 1}, tt.lenA)
+			b := placeSnake(gs, "b", headB, Direction{X: -1}, tt.lenB)
+			if tt.apple {
+				gs.addApple(meet)
+			}
+
+			gs.moveSnakes()
+
+			var alive []string
+			for _, snake := range gs.order {
+				alive = append(alive, snake.ID)
+			}
+			if !reflect.DeepEqual(alive, tt.alive) {
+				t.Fatalf("存活的蛇为 %v，期望 %v", alive, tt.alive)
+			}
+			// 只有存活的蛇获得击杀
+			if kills := a.Kills + b.Kills; kills != len(tt.alive) {
+				t.Errorf("击杀数共 %d，期望 %d", kills, len(tt.alive))
+			}
+			if len(tt.alive) == 1 {
+				winner := map[string]*Snake{"a": a, "b": b}[tt.alive[0]]
+				if len(winner.Body) != tt.length {
+					t.Errorf("存活的蛇长度为 %d，期望 %d", len(winner.Body), tt.length)
+				}
+				if winner.Kills != 1 {
+					t.Errorf("存活的蛇击杀数为 %d，期望 1", winner.Kills)
+				}
+			}
+			if tt.swap {
+				return
+			}
+			if got := gs.grid.apples[gs.grid.index(meet)]; got != tt.apples {
+				t.Errorf("相撞格子上有 %d 个苹果，期望 %d", got, tt.apples)
+			}
+		})
+	}
+}
+
+func TestEatAppleGrows(t *testing.T) {
+	config := DefaultConfig()
+	config.Cols, config.Rows = 20, 10
+	gs := newTestState(t, config)
+	snake := placeSnake(gs, "a", Position{X: 5, Y: 5}, Direction{X: 1}, 3)
+	gs.addApple(Position{X: 6, Y: 5})
+
+	// 吃到苹果时保留尾部，身体是旧的头部加上原来的身体
+	want := append([]Position{{X: 5, Y: 5}}, snake.Body...)
+	gs.moveSnakes()
+	if !reflect.DeepEqual(snake.Body, want) || snake.Apples != 1 || len(gs.apples) != 0 {
+		t.Fatalf("吃苹果后身体为 %v、苹果数 %d、场上苹果 %d，期望 %v、1、0", snake.Body, snake.Apples, len(gs.apples), want)
+	}
+}
+
+func TestEatingTailBlocks(t *testing.T) {
+	config := DefaultConfig()
+	config.Cols, config.Rows = 20, 10
+	gs := newTestState(t, config)
+	// a吃到苹果并保留尾部(2,5)，b的头进入这一格时撞上a的身体
+	a := placeSnake(gs, "a", Position{X: 5, Y: 5}, Direction{X: 1}, 3)
+	placeSnake(gs, "b", Position{X: 2, Y: 4}, Direction{Y: 1}, 3)
+	gs.addApple(Position{X: 6, Y: 5})
+
+	gs.moveSnakes()
+	if _, alive := gs.snakes["b"]; alive {
+		t.Fatal("b撞上吃苹果的蛇保留的尾部后仍然存活")
+	}
+	if a.Kills != 1 || len(a.Body) != 4 {
+		t.Fatalf("a的击杀数 %d、长度 %d，期望 1、4", a.Kills, len(a.Body))
+	}
+}
@@ -10,10 +10,10 @@ const (
 
 // ViewInfo 存储AI蛇的视野信息
 type ViewInfo struct {
-	Center    Position   // 视野中心点（蛇头位置）
-	Food      []Position // 视野范围内的食物位置
-	Snakes    []*Snake   // 视野范围内的其他蛇
-	Obstacles []Position // 视野范围内的障碍物
+	Center    Position    // 视野中心点（蛇头位置）
+	Food      []Position  // 视野范围内的食物位置
+	Snakes    []SnakeInfo // 视野范围内的其他蛇
+	Obstacles []Position  // 视野范围内的障碍物
 }
 
 // PersonalityType 定义AI蛇的性格类型
@@ -111,6 +111,6 @@ func GetPersonalityWeights(p PersonalityType) PersonalityWeights {
 }
 
 // RandomPersonality 随机生成一个性格类型
-func RandomPersonality() PersonalityType {
-	return PersonalityType(rand.Intn(7))
+func RandomPersonality(rng *rand.Rand) PersonalityType {
+	return PersonalityType(rng.Intn(7))
 }
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength 玩家昵称的最大字符数
+const MaxNameLength = 16
+
+var (
+	// ErrInvalidName 昵称包含不可打印字符或过长
+	ErrInvalidName = fmt.Errorf("昵称不能包含控制字符，长度不能超过%d个字符", MaxNameLength)
+	// ErrInvalidColor 颜色不是#RRGGBB格式
+	ErrInvalidColor = errors.New("颜色必须是 #RRGGBB 格式")
+	// ErrNotDead 玩家的蛇还活着，不能复活
+	ErrNotDead = errors.New("蛇还活着，不能复活")
+	// ErrRespawnCooldown 死亡后的等待时间还没有结束
+	ErrRespawnCooldown = errors.New("复活冷却中")
+)
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// PlayerOptions 玩家加入时选择的昵称和颜色，为空时使用随机生成的值
+type PlayerOptions struct {
+	Name  string
+	Color string
+}
+
+// normalize 去除昵称首尾空白并检查昵称和颜色是否合法
+func (o *PlayerOptions) normalize() error {
+	o.Name = strings.TrimSpace(o.Name)
+	if utf8.RuneCountInString(o.Name) > MaxNameLength {
+		return ErrInvalidName
+	}
+	for _, r := range o.Name {
+		if !unicode.IsPrint(r) {
+			return ErrInvalidName
+		}
+	}
+	if o.Color != "" && !colorPattern.MatchString(o.Color) {
+		return ErrInvalidColor
+	}
+	return nil
+}
+
+// uniqueName 在昵称与场上其他蛇重复时追加编号，调用方需持有锁
+// 追加编号前截断昵称，结果不超过MaxNameLength个字符
+func (gs *GameState) uniqueName(name string) string {
+	taken := make(map[string]bool, len(gs.order))
+	for _, snake := range gs.order {
+		taken[snake.Name] = true
+	}
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		suffix := strconv.Itoa(i)
+		base := []rune(name)
+		if n := MaxNameLength - len(suffix); len(base) > n {
+			base = base[:n]
+		}
+		unique = string(base) + suffix
+	}
+	return unique
+}
+
+// RespawnPlayer 为蛇已经死亡的玩家创建一条新蛇，沿用玩家选择的昵称和原来的颜色，没有选择昵称时重新生成随机名称
+// id为玩家死亡的蛇，连接会收到新的会话信息和完整状态
+func (gs *GameState) RespawnPlayer(id string) (*Snake, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	c, ok := gs.clients[id]
+	if !ok {
+		return nil, ErrNotDead
+	}
+	if _, alive := gs.snakes[id]; alive {
+		return nil, ErrNotDead
+	}
+	if gs.tick < c.respawn {
+		return nil, fmt.Errorf("%w，还需等待 %d 个tick", ErrRespawnCooldown, c.respawn-gs.tick)
+	}
+	delete(gs.clients, id)
+	snake, _ := gs.addPlayer(c.conn, c.protocol, c.player)
+	return snake, nil
+}
@@ -0,0 +1,144 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestUniqueNameLength(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"Bob", []string{"Bob", "Bob2", "Bob3"}},
+		{"abcdefghijklmnop", []string{"abcdefghijklmnop", "abcdefghijklmno2", "abcdefghijklmno3"}},
+		{"abcdefghijklmno", []string{"abcdefghijklmno", "abcdefghijklmno2", "abcdefghijklmno3"}},
+		{strings.Repeat("蛇", MaxNameLength), []string{strings.Repeat("蛇", MaxNameLength), strings.Repeat("蛇", MaxNameLength-1) + "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Cols, config.Rows = 60, 60
+			gs := newTestState(t, config)
+			for i, want := range tt.want {
+				snake, _, err := gs.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: tt.name})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if snake.Name != want {
+					t.Fatalf("第 %d 个玩家的昵称为 %q，期望 %q", i+1, snake.Name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUniqueNameTwoDigitSuffix(t *testing.T) {
+	config := DefaultConfig()
+	config.Cols, config.Rows = 60, 60
+	gs := newTestState(t, config)
+	name := strings.Repeat("x", MaxNameLength)
+	seen := make(map[string]bool)
+	for i := 1; i <= 12; i++ {
+		snake, _, err := gs.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := utf8.RuneCountInString(snake.Name); n > MaxNameLength {
+			t.Fatalf("昵称 %q 有 %d 个字符，超过 %d", snake.Name, n, MaxNameLength)
+		}
+		if seen[snake.Name] {
+			t.Fatalf("昵称 %q 重复", snake.Name)
+		}
+		seen[snake.Name] = true
+	}
+	if want := strings.Repeat("x", MaxNameLength-2) + "12"; !seen[want] {
+		t.Fatalf("没有生成昵称 %q", want)
+	}
+}
+
+func TestRespawnPlayer(t *testing.T) {
+	tests := []struct {
+		name    string
+		die     bool   // 玩家的蛇是否死亡
+		leave   bool   // 玩家是否离开了游戏
+		wait    uint64 // 死亡后经过的tick数
+		id      string // 为空时使用玩家的蛇ID
+		wantErr error
+	}{
+		{"蛇还活着", false, false, 0, "", ErrNotDead},
+		{"不存在的玩家", false, false, 0, "s999", ErrNotDead},
+		{"离开游戏后", true, true, 100, "", ErrNotDead},
+		{"冷却中", true, false, 0, "", ErrRespawnCooldown},
+		{"冷却差一个tick", true, false, 19, "", ErrRespawnCooldown},
+		{"冷却结束", true, false, 20, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.UpdateInterval, config.RespawnCooldown = 150, 3 // 冷却为20个tick
+			gs := newTestState(t, config)
+			conn := &recordConn{}
+			snake, token, err := gs.AddPlayer(conn, ProtocolDelta, PlayerOptions{Name: "Bob", Color: "#123456"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.take()
+
+			if tt.die {
+				gs.snakeToApples(snake, nil)
+				gs.broadcastState()
+				// 死亡事件推送给玩家，包含可以复活的tick
+				var death *DeathEvent
+				for _, msg := range conn.take() {
+					if d, ok := msg.(*DeathEvent); ok {
+						death = d
+					}
+				}
+				if death == nil || death.SnakeID != snake.ID || death.RespawnTick != gs.tick+20 {
+					t.Fatalf("玩家收到的死亡事件为 %+v，期望蛇 %s 在tick %d 可以复活", death, snake.ID, gs.tick+20)
+				}
+			}
+			if tt.leave {
+				gs.RemoveSnake(snake.ID)
+			}
+			gs.tick += tt.wait
+
+			id := tt.id
+			if id == "" {
+				id = snake.ID
+			}
+			respawned, err := gs.RespawnPlayer(id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RespawnPlayer() = %v，期望 %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if respawned.ID == snake.ID || respawned.Name != "Bob" || respawned.Color != "#123456" {
+				t.Fatalf("复活的蛇为 %s(%s, %s)，期望新的ID并沿用昵称和颜色", respawned.ID, respawned.Name, respawned.Color)
+			}
+			if _, ok := gs.clients[snake.ID]; ok {
+				t.Fatal("死亡的蛇的客户端没有移除")
+			}
+			if c, ok := gs.clients[respawned.ID]; !ok || c.conn != conn || c.protocol != ProtocolDelta {
+				t.Fatal("复活的蛇没有沿用原来的连接和协议")
+			}
+			// 先收到新的会话信息，再收到从seq 1开始的完整状态
+			msgs := conn.take()
+			if len(msgs) != 2 {
+				t.Fatalf("复活后收到 %d 条消息，期望 2", len(msgs))
+			}
+			session, ok := msgs[0].(*SessionMessage)
+			if !ok || session.SnakeID != respawned.ID || session.Token == "" || session.Token == token {
+				t.Fatalf("第一条消息为 %+v，期望蛇 %s 的新会话", msgs[0], respawned.ID)
+			}
+			if state, ok := msgs[1].(*StateMessage); !ok || state.Seq != 1 || state.Snakes[respawned.ID] == nil {
+				t.Fatalf("第二条消息为 %+v，期望包含复活的蛇、seq为1的完整状态", msgs[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package game
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RecordVersion 录像文件格式版本
+const RecordVersion = 1
+
+// 录像中的输入事件类型
+const (
+	EventJoin      = "join"
+	EventLeave     = "leave"
+	EventDirection = "dir"
+	EventDetach    = "detach"
+	EventResume    = "resume"
+	// EventAITimeout AI蛇在该tick中决策超时，保持了原来的方向
+	EventAITimeout = "timeout"
+)
+
+// RecordHeader 录像文件头，包含复现游戏所需的配置和种子
+type RecordHeader struct {
+	Version int         `json:"version"`
+	Seed    int64       `json:"seed"`
+	Config  *GameConfig `json:"config"`
+	Map     *GameMap    `json:"map,omitempty"` // 游戏使用的地图，没有地图时为空
+}
+
+// RecordEvent 录像中的一条输入事件，Tick为事件发生时已推进的tick数
+type RecordEvent struct {
+	Tick    uint64     `json:"t"`
+	Type    string     `json:"type"`
+	SnakeID string     `json:"id"`
+	Dir     *Direction `json:"dir,omitempty"`
+	// Name、Color 玩家加入时选择的昵称和颜色
+	Name  string `json:"name,omitempty"`
+	Color string `json:"color,omitempty"`
+}
+
+// recordLine 录像文件中的一行，Header、Event、Snapshot三者只有一个非空
+type recordLine struct {
+	Header   *RecordHeader `json:"header,omitempty"`
+	Event    *RecordEvent  `json:"event,omitempty"`
+	Snapshot *Snapshot     `json:"snapshot,omitempty"`
+	// Events 快照写入前已记录的事件数，回放时从这里继续应用事件
+	Events int `json:"events,omitempty"`
+}
+
+// Keyframe 录像中的一个快照及其在事件序列中的位置
+type Keyframe struct {
+	Snapshot *Snapshot
+	Event    int
+}
+
+// Recorder 将游戏输入和定期快照写入gzip压缩的JSON行文件
+type Recorder struct {
+	file     io.WriteCloser
+	zw       *gzip.Writer
+	enc      *json.Encoder
+	interval uint64
+	events   int
+	dirty    bool
+	err      error
+}
+
+// NewRecorder 创建录像器，每隔snapshotInterval个tick写入一次快照
+func NewRecorder(w io.WriteCloser, snapshotInterval int) *Recorder {
+	if snapshotInterval < 1 {
+		snapshotInterval = 1
+	}
+	zw := gzip.NewWriter(w)
+	return &Recorder{
+		file:     w,
+		zw:       zw,
+		enc:      json.NewEncoder(zw),
+		interval: uint64(snapshotInterval),
+	}
+}
+
+// write 写入一行记录，出错后忽略后续写入
+func (r *Recorder) write(line recordLine) {
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(line)
+	r.dirty = true
+}
+
+// writeEvent 写入一条输入事件
+func (r *Recorder) writeEvent(event *RecordEvent) {
+	r.write(recordLine{Event: event})
+	r.events++
+}
+
+// writeSnapshot 写入一个快照及其在事件序列中的位置
+func (r *Recorder) writeSnapshot(snap *Snapshot) {
+	r.write(recordLine{Snapshot: snap, Events: r.events})
+}
+
+// flush 将缓冲的数据刷新到文件，保证进程意外退出时已写入的tick可以回放
+func (r *Recorder) flush() {
+	if r.err != nil || !r.dirty {
+		return
+	}
+	r.err = r.zw.Flush()
+	r.dirty = false
+}
+
+// close 结束gzip流并关闭文件
+func (r *Recorder) close() error {
+	if err := r.zw.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	if err := r.file.Close(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// StartRecording 开始录制，立即写入文件头和当前状态的快照
+func (gs *GameState) StartRecording(r *Recorder) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.recorder != nil {
+		return errors.New("已经在录制中")
+	}
+	r.write(recordLine{Header: &RecordHeader{Version: RecordVersion, Seed: gs.seed, Config: gs.config, Map: gs.config.Map}})
+	r.writeSnapshot(gs.snapshot())
+	r.flush()
+	if r.err != nil {
+		return r.err
+	}
+	gs.recorder = r
+	return nil
+}
+
+// StopRecording 停止录制并关闭录像文件，未在录制时什么也不做
+func (gs *GameState) StopRecording() error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.recorder == nil {
+		return nil
+	}
+	r := gs.recorder
+	gs.recorder = nil
+	r.writeSnapshot(gs.snapshot())
+	return r.close()
+}
+
+// record 记录一条输入事件，调用方需持有锁
+func (gs *GameState) record(eventType, snakeID string, dir *Direction) {
+	gs.recordEvent(&RecordEvent{Type: eventType, SnakeID: snakeID, Dir: dir})
+}
+
+// recordEvent 以当前tick记录一条输入事件，调用方需持有锁
+func (gs *GameState) recordEvent(event *RecordEvent) {
+	if gs.recorder == nil {
+		return
+	}
+	event.Tick = gs.tick
+	gs.recorder.writeEvent(event)
+}
+
+// recordTick 在tick结束时按间隔写入快照并刷新文件，调用方需持有锁
+func (gs *GameState) recordTick() {
+	if gs.recorder == nil {
+		return
+	}
+	if gs.tick%gs.recorder.interval == 0 {
+		gs.recorder.writeSnapshot(gs.snapshot())
+	}
+	gs.recorder.flush()
+}
+
+// Recording 读入内存的录像
+type Recording struct {
+	Header    RecordHeader
+	Events    []RecordEvent
+	Keyframes []Keyframe
+}
+
+// ReadRecording 读取录像文件，文件被截断时返回已读取的部分
+func ReadRecording(r io.Reader) (*Recording, error) {
+	zr, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("读取录像失败: %w", err)
+	}
+	defer zr.Close()
+
+	rec := &Recording{}
+	dec := json.NewDecoder(zr)
+	for {
+		var line recordLine
+		if err := dec.Decode(&line); err != nil {
+			// 服务器异常退出时文件末尾可能不完整，保留之前的内容
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if len(rec.Keyframes) == 0 {
+				return nil, fmt.Errorf("解析录像失败: %w", err)
+			}
+			break
+		}
+		switch {
+		case line.Header != nil:
+			rec.Header = *line.Header
+		case line.Event != nil:
+			rec.Events = append(rec.Events, *line.Event)
+		case line.Snapshot != nil:
+			rec.Keyframes = append(rec.Keyframes, Keyframe{Snapshot: line.Snapshot, Event: line.Events})
+		}
+	}
+
+	if rec.Header.Version != RecordVersion || rec.Header.Config == nil {
+		return nil, fmt.Errorf("不支持的录像版本 %d", rec.Header.Version)
+	}
+	if len(rec.Keyframes) == 0 {
+		return nil, errors.New("录像中没有快照")
+	}
+	if rec.Header.Map != nil {
+		if err := rec.Header.Map.init(); err != nil {
+			return nil, fmt.Errorf("录像中的地图无效: %w", err)
+		}
+		rec.Header.Config.Map = rec.Header.Map
+	}
+	return rec, nil
+}
+
+// StartTick 返回录像开始时的tick
+func (rec *Recording) StartTick() uint64 {
+	return rec.Keyframes[0].Snapshot.Tick
+}
+
+// EndTick 返回录像结束时的tick
+func (rec *Recording) EndTick() uint64 {
+	end := rec.Keyframes[len(rec.Keyframes)-1].Snapshot.Tick
+	if n := len(rec.Events); n > 0 && rec.Events[n-1].Tick > end {
+		end = rec.Events[n-1].Tick
+	}
+	return end
+}
@@ -0,0 +1,116 @@
+package game
+
+import (
+	"log"
+	"sort"
+)
+
+// ReplayPlayer 在内存中重新模拟一段录像，支持逐tick推进和跳转
+type ReplayPlayer struct {
+	rec   *Recording
+	state *GameState
+	next  int // 下一条待应用事件的下标
+}
+
+// NewReplayPlayer 创建回放器并定位到录像开头
+func NewReplayPlayer(rec *Recording) *ReplayPlayer {
+	p := &ReplayPlayer{rec: rec}
+	p.Seek(rec.StartTick())
+	return p
+}
+
+// Tick 返回当前回放到的tick
+func (p *ReplayPlayer) Tick() uint64 {
+	return p.state.Tick()
+}
+
+// State 返回当前tick的游戏状态
+func (p *ReplayPlayer) State() *StateMessage {
+	return p.state.State()
+}
+
+// Step 应用当前tick的输入并推进一个tick，到达录像末尾时返回false
+func (p *ReplayPlayer) Step() bool {
+	tick := p.state.Tick()
+	if tick >= p.rec.EndTick() {
+		return false
+	}
+	for p.next < len(p.rec.Events) && p.rec.Events[p.next].Tick <= tick {
+		p.apply(p.rec.Events[p.next])
+		p.next++
+	}
+	p.state.mu.Lock()
+	p.state.timedOut = p.timeouts(tick + 1)
+	p.state.mu.Unlock()
+	p.state.UpdateGame()
+	return true
+}
+
+// Seek 跳转到指定tick：从不晚于它的最近快照恢复，再模拟到目标tick
+func (p *ReplayPlayer) Seek(tick uint64) {
+	if tick < p.rec.StartTick() {
+		tick = p.rec.StartTick()
+	}
+	if end := p.rec.EndTick(); tick > end {
+		tick = end
+	}
+
+	// 找到最后一个tick不晚于目标的关键帧
+	frames := p.rec.Keyframes
+	i := sort.Search(len(frames), func(i int) bool { return frames[i].Snapshot.Tick > tick }) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	// 当前位置已在关键帧和目标之间时直接向前模拟，避免重复恢复
+	if p.state == nil || p.state.Tick() > tick || p.state.Tick() < frames[i].Snapshot.Tick {
+		p.state = restoreGameState(p.rec.Header.Config, frames[i].Snapshot)
+		p.next = frames[i].Event
+	}
+	for p.state.Tick() < tick && p.Step() {
+	}
+}
+
+// timeouts 返回录像中在指定tick决策超时的AI蛇
+// 超时事件在tick进行中记录，需要在模拟该tick之前提前读取
+func (p *ReplayPlayer) timeouts(tick uint64) map[string]bool {
+	var ids map[string]bool
+	for i := p.next; i < len(p.rec.Events) && p.rec.Events[i].Tick <= tick; i++ {
+		if event := p.rec.Events[i]; event.Type == EventAITimeout && event.Tick == tick {
+			if ids == nil {
+				ids = make(map[string]bool)
+			}
+			ids[event.SnakeID] = true
+		}
+	}
+	return ids
+}
+
+// apply 应用一条录制的输入事件
+func (p *ReplayPlayer) apply(event RecordEvent) {
+	switch event.Type {
+	case EventJoin:
+		snake, _, err := p.state.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: event.Name, Color: event.Color})
+		if err != nil {
+			log.Printf("回放分歧: tick %d 加入 %s 失败: %v", event.Tick, event.SnakeID, err)
+			return
+		}
+		if snake.ID != event.SnakeID {
+			log.Printf("回放分歧: tick %d 期望加入 %s，实际为 %s", event.Tick, event.SnakeID, snake.ID)
+		}
+	case EventLeave:
+		p.state.RemoveSnake(event.SnakeID)
+	case EventDetach:
+		p.state.Disconnect(event.SnakeID, nil)
+	case EventResume:
+		p.state.mu.Lock()
+		p.state.resume(event.SnakeID)
+		p.state.mu.Unlock()
+	case EventDirection:
+		if event.Dir != nil {
+			p.state.UpdateSnakeDirection(event.SnakeID, *event.Dir)
+		}
+	case EventAITimeout:
+		// 已在模拟该tick之前由timeouts读取
+	}
+}
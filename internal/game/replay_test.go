@@ -0,0 +1,77 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// bufferCloser 把录像写入内存
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+// recordGame 录制一局有玩家输入的游戏，返回读回的录像
+func recordGame(t *testing.T, config *GameConfig, ticks int) *Recording {
+	t.Helper()
+	gs, err := NewGameState(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bufferCloser{}
+	if err := gs.StartRecording(NewRecorder(buf, 10)); err != nil {
+		t.Fatal(err)
+	}
+	dirs := []Direction{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+	player, _, err := gs.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: "player"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < ticks; i++ {
+		if i%4 == 0 {
+			gs.UpdateSnakeDirection(player.ID, dirs[(i/4)%len(dirs)])
+		}
+		gs.UpdateGame()
+	}
+	if err := gs.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := ReadRecording(&buf.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestReplaySeekMatchesStep(t *testing.T) {
+	for _, strategy := range []string{StrategyPersonality, StrategyGreedy} {
+		for _, boundary := range []Boundary{BoundaryWrap, BoundaryWalls} {
+			for seed := int64(1); seed <= 4; seed++ {
+				t.Run(fmt.Sprintf("%s/%s/%d", strategy, boundary, seed), func(t *testing.T) {
+					config := DefaultConfig()
+					config.Cols, config.Rows = 40, 40
+					config.InitialAICount, config.MaxAICount = 12, 16
+					config.AIStrategy = strategy
+					config.Boundary = boundary
+					config.Seed = seed
+					rec := recordGame(t, config, 100)
+
+					stepped := NewReplayPlayer(rec)
+					for tick := rec.StartTick(); tick <= rec.EndTick(); tick++ {
+						if stepped.Tick() != tick {
+							t.Fatalf("逐tick回放停在 %d，期望 %d", stepped.Tick(), tick)
+						}
+						seeked := NewReplayPlayer(rec)
+						seeked.Seek(tick)
+						if got, want := mustJSON(t, seeked.state.Snapshot()), mustJSON(t, stepped.state.Snapshot()); got != want {
+							t.Fatalf("跳转到tick %d 的状态与逐tick回放不一致", tick)
+						}
+						stepped.Step()
+					}
+				})
+			}
+		}
+	}
+}
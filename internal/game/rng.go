@@ -0,0 +1,35 @@
+package game
+
+import "math/rand"
+
+// source 可导出内部状态的随机数源(splitmix64)
+// 与math/rand的默认源不同，它的状态只有一个uint64，便于快照和回放时恢复
+type source struct {
+	state uint64
+}
+
+// newRand 使用给定种子创建随机数生成器及其随机数源
+func newRand(seed int64) (*rand.Rand, *source) {
+	src := &source{}
+	src.Seed(seed)
+	return rand.New(src), src
+}
+
+// Seed 实现rand.Source接口
+func (s *source) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+// Uint64 实现rand.Source64接口
+func (s *source) Uint64() uint64 {
+	s.state += 0x9E3779B97F4A7C15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// Int63 实现rand.Source接口
+func (s *source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
@@ -0,0 +1,123 @@
+package game
+
+import "sort"
+
+const (
+	// AppleScore 吃到一个苹果获得的分数
+	AppleScore = 1
+	// KillScore 击杀一条蛇获得的分数
+	KillScore = 10
+)
+
+// LeaderboardEntry 排行榜上的一条蛇
+type LeaderboardEntry struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	Color     string  `json:"color"`
+	IsAI      bool    `json:"isAI"`
+	Score     int     `json:"score"`
+	Kills     int     `json:"kills"`
+	Apples    int     `json:"apples"`
+	Length    int     `json:"length"`
+	MaxLength int     `json:"maxLength"`
+	Alive     float64 `json:"alive"` // 存活时间(秒)
+}
+
+// LeaderboardMessage 按分数排序的前N条存活的蛇，包括AI和玩家
+type LeaderboardMessage struct {
+	Tick    uint64             `json:"tick"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// PlayerResult 玩家的一条蛇从出生到死亡或离开的成绩
+type PlayerResult struct {
+	Name      string // 玩家选择的昵称，不含场上去重时追加的编号
+	Score     int
+	Kills     int
+	Apples    int
+	MaxLength int
+	Alive     float64 // 存活时间(秒)
+}
+
+// ResultHandler 接收玩家成绩的回调，在持有游戏锁时调用，不能阻塞
+type ResultHandler func(PlayerResult)
+
+// SetResultHandler 设置玩家的蛇死亡或离开时接收成绩的回调
+// AI蛇和没有选择昵称的玩家的成绩不会上报
+func (gs *GameState) SetResultHandler(handler ResultHandler) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.onResult = handler
+}
+
+// reportResult 上报玩家的蛇的成绩，调用方需持有锁
+func (gs *GameState) reportResult(snake *Snake) {
+	if snake.IsAI || snake.nickname == "" || gs.onResult == nil {
+		return
+	}
+	gs.onResult(PlayerResult{
+		Name:      snake.nickname,
+		Score:     snake.Score,
+		Kills:     snake.Kills,
+		Apples:    snake.Apples,
+		MaxLength: snake.MaxLength,
+		Alive:     gs.aliveSeconds(snake),
+	})
+}
+
+// eatApple 记录蛇吃到一个苹果，调用方需持有锁
+func (s *Snake) eatApple() {
+	s.Apples++
+	s.Score += AppleScore
+}
+
+// creditKill 将击杀记在撞上的蛇上，调用方需持有锁
+func (s *Snake) creditKill() {
+	s.Kills++
+	s.Score += KillScore
+}
+
+// updateMaxLength 在蛇移动后更新最大长度，调用方需持有锁
+func (s *Snake) updateMaxLength() {
+	if len(s.Body) > s.MaxLength {
+		s.MaxLength = len(s.Body)
+	}
+}
+
+// aliveSeconds 返回蛇到当前tick为止的存活时间(秒)，调用方需持有锁
+func (gs *GameState) aliveSeconds(snake *Snake) float64 {
+	return float64((gs.tick-snake.BornTick)*uint64(gs.config.UpdateInterval)) / 1000
+}
+
+// leaderboard 生成分数最高的前N条蛇，分数相同时按长度排序，调用方需持有锁
+func (gs *GameState) leaderboard() *LeaderboardMessage {
+	entries := make([]LeaderboardEntry, 0, len(gs.order))
+	for _, snake := range gs.order {
+		if snake.Dead {
+			continue
+		}
+		entries = append(entries, LeaderboardEntry{
+			ID:        snake.ID,
+			Name:      snake.Name,
+			Color:     snake.Color,
+			IsAI:      snake.IsAI,
+			Score:     snake.Score,
+			Kills:     snake.Kills,
+			Apples:    snake.Apples,
+			Length:    len(snake.Body),
+			MaxLength: snake.MaxLength,
+			Alive:     gs.aliveSeconds(snake),
+		})
+	}
+	// order按创建顺序排列，稳定排序保证同分同长度时先出生的蛇在前
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		return entries[i].Length > entries[j].Length
+	})
+	if len(entries) > gs.config.LeaderboardSize {
+		entries = entries[:gs.config.LeaderboardSize]
+	}
+	return &LeaderboardMessage{Tick: gs.tick, Entries: entries}
+}
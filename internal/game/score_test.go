@@ -0,0 +1,44 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReportResultUsesNickname(t *testing.T) {
+	gs := newTestState(t, DefaultConfig())
+	var names []string
+	gs.SetResultHandler(func(result PlayerResult) {
+		names = append(names, result.Name)
+	})
+
+	first, _, _ := gs.AddPlayer(&recordConn{}, ProtocolFull, PlayerOptions{Name: "Bob"})
+	second, _, _ := gs.AddPlayer(&recordConn{}, ProtocolFull, PlayerOptions{Name: "Bob"})
+	anonymous, _, _ := gs.AddPlayer(&recordConn{}, ProtocolFull, PlayerOptions{})
+	if second.Name != "Bob2" {
+		t.Fatalf("重复的昵称为 %q，期望 Bob2", second.Name)
+	}
+	snakes := []*Snake{first, second, anonymous}
+	// 死亡后复活，复活的蛇仍然按选择的昵称上报，匿名玩家复活后仍然不上报
+	for _, snake := range snakes {
+		gs.snakeToApples(snake, nil)
+	}
+	gs.tick += gs.config.Ticks(gs.config.RespawnCooldown)
+	for i, snake := range snakes {
+		respawned, err := gs.RespawnPlayer(snake.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		snakes[i] = respawned
+	}
+	if snakes[1].Name != "Bob2" || snakes[1].nickname != "Bob" || snakes[2].nickname != "" {
+		t.Fatalf("复活后的昵称为 %q/%q、匿名玩家为 %q，期望 Bob2/Bob 和空", snakes[1].Name, snakes[1].nickname, snakes[2].nickname)
+	}
+	for _, snake := range snakes {
+		gs.RemoveSnake(snake.ID)
+	}
+
+	if want := []string{"Bob", "Bob", "Bob", "Bob"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("上报的昵称为 %q，期望 %q", names, want)
+	}
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+)
+
+// SessionMessage 玩家加入或恢复时收到的会话信息，断线重连时凭Token找回自己的蛇
+type SessionMessage struct {
+	Token   string
+	SnakeID string
+	Name    string
+	Color   string
+	Config  *GameConfig
+	Map     *GameMap
+}
+
+// newSessionToken 生成随机的会话令牌
+// 令牌不参与模拟，因此使用crypto/rand而不是游戏的rng
+func newSessionToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Println("生成会话令牌失败:", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// attach 为蛇创建客户端，先发送会话信息再发送完整状态，调用方需持有锁
+func (gs *GameState) attach(snake *Snake, conn Connection, protocol Protocol, token string) {
+	c := newClient(conn, protocol, Position{X: snake.X, Y: snake.Y})
+	c.follow = snake.ID
+	c.player = PlayerOptions{Name: snake.nickname, Color: snake.Color}
+	gs.clients[snake.ID] = c
+	conn.WriteJSON(&SessionMessage{
+		Token:   token,
+		SnakeID: snake.ID,
+		Name:    snake.Name,
+		Color:   snake.Color,
+		Config:  gs.config,
+		Map:     gs.config.Map,
+	})
+	gs.sendState(c)
+}
+
+// ResumePlayer 凭会话令牌将新的连接重新关联到断线前的蛇
+// 令牌无效或蛇已经死亡时返回nil，调用方应改为创建新的玩家
+func (gs *GameState) ResumePlayer(token string, conn Connection, protocol Protocol) *Snake {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	id, ok := gs.sessions[token]
+	if !ok {
+		return nil
+	}
+	snake, ok := gs.snakes[id]
+	if !ok {
+		return nil
+	}
+	// 旧连接可能还没有被发现断开，关闭它，之后它的断开不再影响这条蛇
+	if old, ok := gs.clients[id]; ok {
+		go old.conn.Close()
+	}
+	gs.resume(id)
+	snake.Conn = conn
+	if conn != nil {
+		gs.attach(snake, conn, protocol, token)
+	}
+	return snake
+}
+
+// Disconnect 处理玩家连接断开
+// 配置了会话保留时间时蛇被冻结，等待玩家重连，否则立即移除。
+// 连接已被新的连接替换时什么也不做。
+func (gs *GameState) Disconnect(id string, conn Connection) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if c, ok := gs.clients[id]; ok && c.conn != conn {
+		return
+	}
+	gs.disconnect(id)
+}
+
+// disconnect 冻结或移除断开连接的玩家，调用方需持有锁
+func (gs *GameState) disconnect(id string) {
+	delete(gs.clients, id)
+	snake, ok := gs.snakes[id]
+	if !ok || snake.Frozen {
+		return
+	}
+	if gs.config.SessionGrace <= 0 {
+		gs.record(EventLeave, id, nil)
+		gs.snakeToApples(snake, nil)
+		return
+	}
+	gs.record(EventDetach, id, nil)
+	snake.Frozen = true
+	snake.Conn = nil
+	snake.Inputs = nil
+	gs.detached[id] = gs.tick
+}
+
+// resume 解除蛇的冻结状态，调用方需持有锁
+func (gs *GameState) resume(id string) {
+	snake, ok := gs.snakes[id]
+	if !ok || !snake.Frozen {
+		return
+	}
+	gs.record(EventResume, id, nil)
+	snake.Frozen = false
+	delete(gs.detached, id)
+}
+
+// expireDetached 移除超过会话保留时间仍未重连的蛇，调用方需持有锁
+func (gs *GameState) expireDetached() {
+	if len(gs.detached) == 0 {
+		return
+	}
+	grace := gs.config.Ticks(gs.config.SessionGrace)
+	snakes := append([]*Snake(nil), gs.order...)
+	for _, snake := range snakes {
+		if since, ok := gs.detached[snake.ID]; ok && gs.tick-since >= grace {
+			gs.snakeToApples(snake, nil)
+		}
+	}
+}
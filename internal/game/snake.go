@@ -1,9 +1,6 @@
 package game
 
-import (
-	"math/rand"
-	"time"
-)
+import "fmt"
 
 // Snake 表示一条蛇
 type Snake struct {
@@ -16,23 +13,43 @@ type Snake struct {
 	Direction   Direction       `json:"direction"`
 	Body        []Position      `json:"body"`
 	Dead        bool            `json:"dead"`
+	Frozen      bool            `json:"frozen,omitempty"` // 玩家断线，等待重连
+	Inputs      []Direction     `json:"-"`                // 尚未应用的转向输入，不推送给客户端
 	Conn        Connection      `json:"-"`
 	Personality PersonalityType `json:"personality"`
+	BornTick    uint64          `json:"bornTick"` // 出生时的tick
+	Score       int             `json:"score"`
+	Kills       int             `json:"kills"`     // 击杀的蛇的数量
+	Apples      int             `json:"apples"`    // 吃到的苹果数量
+	MaxLength   int             `json:"maxLength"` // 达到过的最大长度
+
+	rank     uint64   // 加入游戏的顺序，与GameState.order中的顺序一致
+	strategy Strategy // AI蛇的策略实例，第一次决策时按配置创建
+	nickname string   // 玩家加入时选择的昵称，未去重，为空表示没有选择
 }
 
-// CreateSnake 创建一条新蛇
-func CreateSnake(isAI bool) *Snake {
-	config := DefaultConfig()
-	x := rand.Intn(config.Cols)
-	y := rand.Intn(config.Rows)
+// createSnake 使用游戏状态的配置和随机数生成器创建一条新蛇，调用方需持有锁
+func (gs *GameState) createSnake(isAI bool) *Snake {
+	config := gs.config
+	x := gs.rng.Intn(config.Cols)
+	y := gs.rng.Intn(config.Rows)
 	dirs := []Direction{{0, -1}, {0, 1}, {-1, 0}, {1, 0}}
-	dir := dirs[rand.Intn(len(dirs))]
+	dir := dirs[gs.rng.Intn(len(dirs))]
+	// 场地在移动方向上只有一格时改为沿另一条轴移动
+	if dir.X != 0 && config.Cols == 1 || dir.Y != 0 && config.Rows == 1 {
+		dir = Direction{X: dir.Y, Y: dir.X}
+	}
+	// 有地图时在出生区域或空地上出生
+	if config.Map != nil {
+		pos := gs.randomOpen(config.Map.Spawns)
+		x, y = pos.X, pos.Y
+	}
 
-	personality := RandomPersonality()
+	personality := RandomPersonality(gs.rng)
 
 	snake := &Snake{
-		ID:          generateID(),
-		Name:        getRandName(),
+		ID:          gs.generateID(),
+		Name:        getRandName(gs.rng),
 		Color:       PersonalityColor[personality], // 使用性格对应的颜色
 		IsAI:        isAI,
 		X:           x,
@@ -40,26 +57,77 @@ func CreateSnake(isAI bool) *Snake {
 		Direction:   dir,
 		Body:        make([]Position, 0),
 		Personality: personality,
+		BornTick:    gs.tick,
 	}
 
 	snakeLen := config.InitialSnakeLength
 	if !isAI {
 		snakeLen = 20
 	}
+	// 蛇身不能长于移动方向上的场地长度，否则会首尾相接
+	if dir.X != 0 && snakeLen > config.Cols-1 {
+		snakeLen = config.Cols - 1
+	}
+	if dir.Y != 0 && snakeLen > config.Rows-1 {
+		snakeLen = config.Rows - 1
+	}
+	// 至少保留蛇头所在的一节
+	if snakeLen < 1 {
+		snakeLen = 1
+	}
+
+	if config.Map != nil {
+		gs.layOnMap(snake, snakeLen)
+		snake.MaxLength = len(snake.Body)
+		return snake
+	}
+
+	// 有墙时让蛇朝向较远的墙，并将蛇头移到让整条蛇留在地图内的位置
+	if config.walls() {
+		if dir.X*(2*x-config.Cols+1) > 0 || dir.Y*(2*y-config.Rows+1) > 0 {
+			dir = dir.Opposite()
+			snake.Direction = dir
+		}
+		n := snakeLen - 1
+		x = clamp(x, dir.X*n, config.Cols-1+dir.X*n)
+		y = clamp(y, dir.Y*n, config.Rows-1+dir.Y*n)
+		snake.X, snake.Y = x, y
+	}
 
 	// 初始化蛇身
 	for i := 0; i < snakeLen; i++ {
-		snake.Body = append(snake.Body, Position{
-			X: (x - dir.X*i + config.Cols) % config.Cols,
-			Y: (y - dir.Y*i + config.Rows) % config.Rows,
-		})
+		snake.Body = append(snake.Body, config.normalize(Position{X: x - dir.X*i, Y: y - dir.Y*i}))
 	}
 
+	snake.MaxLength = len(snake.Body)
+
 	return snake
 }
 
-// generateID 生成唯一的蛇ID
-func generateID() string {
-	return time.Now().Format("20060102150405") +
-		string(rune(rand.Intn(26)+'A'))
+// layOnMap 在有地图时铺设蛇身：选择前方没有墙的方向，从蛇头向后铺设，遇到墙时截断
+func (gs *GameState) layOnMap(snake *Snake, snakeLen int) {
+	dirs := []Direction{snake.Direction, {-snake.Direction.Y, snake.Direction.X}, {snake.Direction.Y, -snake.Direction.X}, snake.Direction.Opposite()}
+	for _, dir := range dirs {
+		if _, ok := gs.config.step(snake.X, snake.Y, dir); ok {
+			snake.Direction = dir
+			break
+		}
+	}
+	pos := Position{X: snake.X, Y: snake.Y}
+	snake.Body = append(snake.Body, pos)
+	for len(snake.Body) < snakeLen {
+		next, ok := gs.config.step(pos.X, pos.Y, snake.Direction.Opposite())
+		// 环形地图上蛇身绕回蛇头时同样截断
+		if !ok || next == snake.Body[0] {
+			break
+		}
+		pos = next
+		snake.Body = append(snake.Body, pos)
+	}
+}
+
+// generateID 生成游戏状态内唯一的蛇ID，调用方需持有锁
+func (gs *GameState) generateID() string {
+	gs.nextID++
+	return fmt.Sprintf("s%d", gs.nextID)
 }
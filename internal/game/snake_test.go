@@ -0,0 +1,79 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+// checkInBounds 检查所有蛇的头部和身体都在场地以内
+func checkInBounds(t *testing.T, gs *GameState) {
+	t.Helper()
+	inside := func(pos Position) bool {
+		return pos.X >= 0 && pos.X < gs.config.Cols && pos.Y >= 0 && pos.Y < gs.config.Rows
+	}
+	for _, snake := range gs.order {
+		if !inside(Position{X: snake.X, Y: snake.Y}) {
+			t.Fatalf("tick %d 蛇 %s 的头部 (%d,%d) 超出场地", gs.tick, snake.ID, snake.X, snake.Y)
+		}
+		for _, pos := range snake.Body {
+			if !inside(pos) {
+				t.Fatalf("tick %d 蛇 %s 的身体 %v 超出场地", gs.tick, snake.ID, pos)
+			}
+		}
+	}
+}
+
+func TestTinyBoards(t *testing.T) {
+	tests := []struct {
+		cols, rows int
+		length     int
+		ai         int
+	}{
+		{1, 10, 3, 1},
+		{10, 1, 3, 1},
+		{1, 2, 1, 1},
+		{2, 2, 2, 1},
+		{3, 3, 3, 1},  // 蛇长等于列数
+		{4, 12, 6, 2}, // 蛇长大于列数
+	}
+	for _, boundary := range []Boundary{BoundaryWrap, BoundaryWalls} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%dx%d", boundary, tt.cols, tt.rows), func(t *testing.T) {
+				for seed := int64(1); seed <= 20; seed++ {
+					config := DefaultConfig()
+					config.Cols, config.Rows = tt.cols, tt.rows
+					config.InitialSnakeLength = tt.length
+					config.InitialAICount, config.MaxAICount = tt.ai, tt.ai
+					config.Boundary = boundary
+					config.Seed = seed
+					if err := config.Validate(); err != nil {
+						t.Fatal(err)
+					}
+					gs, err := NewGameState(config)
+					if err != nil {
+						t.Fatal(err)
+					}
+					for _, snake := range gs.order {
+						if len(snake.Body) == 0 {
+							t.Fatalf("种子 %d 的蛇 %s 没有身体", seed, snake.ID)
+						}
+						// 有墙时新生成的蛇不会一出生就朝向墙
+						if _, ok := config.step(snake.X, snake.Y, snake.Direction); config.walls() && !ok {
+							t.Fatalf("种子 %d 的蛇 %s 在 (%d,%d) 朝向墙 %v", seed, snake.ID, snake.X, snake.Y, snake.Direction)
+						}
+					}
+					checkInBounds(t, gs)
+					// 玩家的蛇长度固定为20，超过场地的长和宽
+					if _, _, err := gs.AddPlayer(nil, ProtocolFull, PlayerOptions{}); err != nil && err != ErrNoSpawn {
+						t.Fatal(err)
+					}
+					checkInBounds(t, gs)
+					for i := 0; i < 30; i++ {
+						gs.UpdateGame()
+						checkInBounds(t, gs)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import "log"
+
+// Snapshot 游戏状态在两个tick之间的完整快照
+// 相同种子和相同输入下，同一tick的快照序列化结果完全一致
+type Snapshot struct {
+	Tick   uint64      `json:"tick"`
+	Seed   int64       `json:"seed"`
+	RNG    uint64      `json:"rng"`
+	NextID uint64      `json:"nextId"`
+	Snakes []*Snake    `json:"snakes"`
+	Apples []AppleInfo `json:"apples"`
+	// Detached 断线等待重连的玩家蛇及其断线时的tick
+	Detached map[string]uint64 `json:"detached,omitempty"`
+	// Inputs 玩家蛇尚未应用的转向输入，Snake序列化时不包含它们
+	Inputs map[string][]Direction `json:"inputs,omitempty"`
+	// Strategies AI蛇实现了StatefulStrategy的策略状态
+	Strategies map[string][]byte `json:"strategies,omitempty"`
+}
+
+// Snapshot 返回当前游戏状态的深拷贝快照
+func (gs *GameState) Snapshot() *Snapshot {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.snapshot()
+}
+
+// snapshot 生成快照，调用方需持有锁
+func (gs *GameState) snapshot() *Snapshot {
+	snap := &Snapshot{
+		Tick:   gs.tick,
+		Seed:   gs.seed,
+		RNG:    gs.src.state,
+		NextID: gs.nextID,
+		Snakes: make([]*Snake, 0, len(gs.order)),
+		Apples: append([]AppleInfo(nil), gs.apples...),
+	}
+	if len(gs.detached) > 0 {
+		snap.Detached = make(map[string]uint64, len(gs.detached))
+		for id, tick := range gs.detached {
+			snap.Detached[id] = tick
+		}
+	}
+	for _, snake := range gs.order {
+		copied := *snake
+		copied.Body = append([]Position(nil), snake.Body...)
+		copied.Inputs = append([]Direction(nil), snake.Inputs...)
+		if len(snake.Inputs) > 0 {
+			if snap.Inputs == nil {
+				snap.Inputs = make(map[string][]Direction)
+			}
+			snap.Inputs[snake.ID] = copied.Inputs
+		}
+		if stateful, ok := snake.strategy.(StatefulStrategy); ok {
+			data, err := stateful.MarshalState()
+			if err != nil {
+				// 没有保存状态的蛇恢复后重新创建策略实例
+				log.Printf("保存蛇 %s 的策略状态失败: %v", snake.ID, err)
+			} else {
+				if snap.Strategies == nil {
+					snap.Strategies = make(map[string][]byte)
+				}
+				snap.Strategies[snake.ID] = append([]byte(nil), data...)
+			}
+		}
+		copied.Conn = nil
+		copied.strategy = nil
+		snap.Snakes = append(snap.Snakes, &copied)
+	}
+	return snap
+}
+
+// restoreGameState 从快照恢复游戏状态，恢复后的模拟与录制时完全一致
+func restoreGameState(config *GameConfig, snap *Snapshot) *GameState {
+	rng, src := newRand(snap.Seed)
+	src.state = snap.RNG
+	gs := &GameState{
+		snakes:     make(map[string]*Snake),
+		apples:     append([]AppleInfo(nil), snap.Apples...),
+		grid:       newGrid(config.Cols, config.Rows),
+		config:     config,
+		seed:       snap.Seed,
+		rng:        rng,
+		src:        src,
+		tick:       snap.Tick,
+		nextID:     snap.NextID,
+		clients:    make(map[string]*client),
+		spectators: make(map[string]*client),
+		sessions:   make(map[string]string),
+		detached:   make(map[string]uint64),
+	}
+	for _, apple := range gs.apples {
+		gs.grid.addApple(apple.Position)
+	}
+	for id, tick := range snap.Detached {
+		gs.detached[id] = tick
+	}
+	for _, snake := range snap.Snakes {
+		copied := *snake
+		copied.Body = append([]Position(nil), snake.Body...)
+		copied.Inputs = append([]Direction(nil), snap.Inputs[snake.ID]...)
+		if data, ok := snap.Strategies[snake.ID]; ok {
+			strategy, err := restoreStrategy(config.AIStrategy, data)
+			if err != nil {
+				log.Printf("蛇 %s 的策略重新创建: %v", snake.ID, err)
+			}
+			copied.strategy = strategy
+		}
+		gs.addSnake(&copied)
+	}
+	return gs
+}
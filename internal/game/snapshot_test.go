@@ -0,0 +1,48 @@
+package game
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInputsHiddenButSnapshotted(t *testing.T) {
+	gs := newTestState(t, DefaultConfig())
+	snake, _, _ := gs.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: "p"})
+	turn := Direction{X: -snake.Direction.Y, Y: snake.Direction.X}
+	if err := gs.UpdateSnakeDirection(snake.ID, turn); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := json.Marshal(gs.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(state), `"inputs"`) {
+		t.Fatalf("状态消息中包含尚未应用的输入: %s", state)
+	}
+
+	data, err := json.Marshal(gs.Snapshot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	restored := restoreGameState(gs.config, &snap)
+	if got, want := restored.snakes[snake.ID].Inputs, []Direction{turn}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("恢复后的输入为 %v，期望 %v", got, want)
+	}
+}
+
+// mustJSON 序列化v，失败时结束测试
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
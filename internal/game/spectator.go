@@ -0,0 +1,80 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownSnake 要跟随的蛇不存在或已经死亡
+var ErrUnknownSnake = errors.New("蛇不存在或已经死亡")
+
+// SpectatorMessage 观众加入时收到的信息
+type SpectatorMessage struct {
+	ID     string
+	Follow string
+	Config *GameConfig
+	Map    *GameMap
+}
+
+// AddSpectator 添加一个不控制蛇的观众，接收和玩家相同的广播
+// follow为要跟随的蛇ID，为空或蛇不存在时视野位于地图中央，返回观众的ID
+func (gs *GameState) AddSpectator(conn Connection, protocol Protocol, follow string) string {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	// 观众不参与模拟，使用单独的计数器，不影响蛇ID的生成
+	gs.nextSpectator++
+	id := fmt.Sprintf("v%d", gs.nextSpectator)
+
+	c := newClient(conn, protocol, Position{X: gs.config.Cols / 2, Y: gs.config.Rows / 2})
+	if snake, ok := gs.snakes[follow]; ok && !snake.Dead {
+		c.follow = follow
+		c.center = Position{X: snake.X, Y: snake.Y}
+	}
+	gs.spectators[id] = c
+	conn.WriteJSON(&SpectatorMessage{ID: id, Follow: c.follow, Config: gs.config, Map: gs.config.Map})
+	gs.sendState(c)
+	return id
+}
+
+// Follow 让观众的视野跟随一条蛇
+func (gs *GameState) Follow(id, snakeID string) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	c, ok := gs.spectators[id]
+	if !ok {
+		return nil
+	}
+	snake, ok := gs.snakes[snakeID]
+	if !ok || snake.Dead {
+		return ErrUnknownSnake
+	}
+	c.follow = snakeID
+	c.center = Position{X: snake.X, Y: snake.Y}
+	return nil
+}
+
+// MoveCamera 停止跟随并将观众的视野中心移动到指定位置，超出地图的坐标在环形地图上取模，有墙时限制在地图以内
+func (gs *GameState) MoveCamera(id string, center Position) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	c, ok := gs.spectators[id]
+	if !ok {
+		return
+	}
+	c.follow = ""
+	c.center = gs.config.normalize(center)
+}
+
+// RemoveSpectator 观众断开连接后停止推送
+func (gs *GameState) RemoveSpectator(id string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	delete(gs.spectators, id)
+}
+
+// SpectatorCount 返回当前观众的数量
+func (gs *GameState) SpectatorCount() int {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return len(gs.spectators)
+}
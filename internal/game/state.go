@@ -1,334 +1,394 @@
 package game
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"math/rand"
 	"sync"
 	"time"
 )
 
 // GameState 实现了游戏状态管理
+//
+// 游戏的推进完全由tick驱动：所有随机数来自以种子初始化的rng，所有计时以tick计，
+// 蛇按创建顺序处理。因此给定相同的种子和每个tick的相同输入，模拟结果完全一致。
 type GameState struct {
 	snakes map[string]*Snake
+	order  []*Snake // 按创建顺序排列的蛇，保证遍历顺序确定
 	apples []AppleInfo
+	grid   *grid // 蛇和苹果所在格子的索引，与snakes和apples同步更新
 	mu     sync.Mutex
 	config *GameConfig
+
+	seed   int64
+	rng    *rand.Rand
+	src    *source
+	tick   uint64
+	nextID uint64
+	ranks  uint64 // 已加入游戏的蛇的数量，用作蛇的rank
+
+	recorder *Recorder
+	onResult ResultHandler
+	world    *WorldView      // 本tick中AI决策使用的只读局面
+	timedOut map[string]bool // 回放时下一个tick中录制为决策超时的AI蛇
+	metrics  tickMetrics
+
+	clients  map[string]*client // 以玩家蛇ID为键的客户端
+	died     []string           // 上次广播以来死亡的蛇
+	deaths   []*DeathEvent      // 上次广播以来的死亡事件
+	kills    []*KillEvent       // 上次广播以来的击杀事件
+	sessions map[string]string  // 会话令牌到玩家蛇ID的映射
+	detached map[string]uint64  // 断线等待重连的玩家蛇及其断线时的tick
+
+	// 以观众ID为键的观众，观众ID使用单独的计数器生成，不影响模拟
+	spectators    map[string]*client
+	nextSpectator uint64
 }
 
 type AppleInfo struct {
-	Position  Position
-	CreatedAt time.Time
+	Position    Position `json:"position"`
+	CreatedTick uint64   `json:"createdTick"`
 }
 
-// NewGameState 创建一个新的游戏状态
-func NewGameState() *GameState {
+// maxSpawnAttempts 创建游戏时为一条初始AI蛇寻找出生位置的最多次数
+const maxSpawnAttempts = 100
+
+// ErrNoSpawn 场地上找不到不与其他蛇重叠的出生位置
+var ErrNoSpawn = errors.New("找不到空闲的出生位置")
+
+// NewGameState 使用给定配置创建一个新的游戏状态
+// 无法为所有初始AI蛇找到出生位置时返回ErrNoSpawn
+func NewGameState(config *GameConfig) (*GameState, error) {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng, src := newRand(seed)
 	gs := &GameState{
-		snakes: make(map[string]*Snake),
-		apples: make([]AppleInfo, 0),
-		config: DefaultConfig(),
+		snakes:     make(map[string]*Snake),
+		apples:     make([]AppleInfo, 0),
+		grid:       newGrid(config.Cols, config.Rows),
+		config:     config,
+		seed:       seed,
+		rng:        rng,
+		src:        src,
+		clients:    make(map[string]*client),
+		sessions:   make(map[string]string),
+		detached:   make(map[string]uint64),
+		spectators: make(map[string]*client),
 	}
 
-	// 初始化时添加AI蛇
+	// 初始化时添加AI蛇，位置无效时重试，超过次数时放弃
 	for i := 0; i < gs.config.InitialAICount; i++ {
-		// 创建新的AI蛇
-		snake := CreateSnake(true)
-
-		// 检查生成位置是否与其他蛇重叠
-		isValidPosition := true
-		for _, existingSnake := range gs.snakes {
-			if !existingSnake.Dead {
-				// 检查头部位置
-				if snake.X == existingSnake.X && snake.Y == existingSnake.Y {
-					isValidPosition = false
-					break
-				}
-				// 检查身体位置
-				for _, segment := range existingSnake.Body {
-					if snake.X == segment.X && snake.Y == segment.Y {
-						isValidPosition = false
-						break
-					}
-				}
+		attempts := 0
+		for {
+			snake := gs.createSnake(true)
+			if gs.isValidSpawn(snake) {
+				gs.addSnake(snake)
+				break
+			}
+			if attempts++; attempts >= maxSpawnAttempts {
+				return nil, fmt.Errorf("第%d条AI蛇尝试%d次后仍%w", i+1, attempts, ErrNoSpawn)
 			}
 		}
+	}
 
-		// 如果位置有效，则添加到游戏中
-		if isValidPosition {
-			gs.snakes[snake.ID] = snake
-		} else {
-			// 如果位置无效，重试这一次
-			i--
+	return gs, nil
+}
+
+// Run 按配置的时间间隔推进游戏，直到ctx被取消
+func (gs *GameState) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(gs.config.UpdateInterval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := gs.StopRecording(); err != nil {
+				log.Println("关闭录像失败:", err)
+			}
+			return
+		case <-ticker.C:
+			gs.UpdateGame()
 		}
 	}
+}
+
+// Config 返回游戏状态使用的配置
+func (gs *GameState) Config() *GameConfig {
+	return gs.config
+}
 
-	go gs.spawnAISnakes()
-	go gs.spawnApples() // 启动苹果生成器
-	return gs
+// Seed 返回游戏状态实际使用的随机数种子
+func (gs *GameState) Seed() int64 {
+	return gs.seed
 }
 
-// AddSnake 添加一条蛇到游戏中
-func (gs *GameState) AddSnake(snake *Snake) {
+// State 返回当前的完整游戏状态
+func (gs *GameState) State() *StateMessage {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	gs.snakes[snake.ID] = snake
-	gs.broadcastState()
+	return gs.stateMessage()
 }
 
-// RemoveSnake 从游戏中移除一条蛇
+// Tick 返回已经推进的tick数
+func (gs *GameState) Tick() uint64 {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.tick
+}
+
+// PlayerCount 返回当前玩家控制的蛇的数量
+func (gs *GameState) PlayerCount() int {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	count := 0
+	for _, snake := range gs.order {
+		if !snake.IsAI && !snake.Dead {
+			count++
+		}
+	}
+	return count
+}
+
+// AICount 返回当前AI蛇的数量
+func (gs *GameState) AICount() int {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.aiCount()
+}
+
+// aiCount 统计存活的AI蛇数量，调用方需持有锁
+func (gs *GameState) aiCount() int {
+	count := 0
+	for _, snake := range gs.order {
+		if snake.IsAI && !snake.Dead {
+			count++
+		}
+	}
+	return count
+}
+
+// AddPlayer 为一个玩家连接创建蛇并加入游戏，返回蛇和用于断线重连的会话令牌
+// 昵称与场上其他蛇重复时自动追加编号。连接会立即收到会话信息和一份完整状态
+func (gs *GameState) AddPlayer(conn Connection, protocol Protocol, opts PlayerOptions) (*Snake, string, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, "", err
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	snake, token := gs.addPlayer(conn, protocol, opts)
+	return snake, token, nil
+}
+
+// addPlayer 使用已检查过的昵称和颜色创建玩家的蛇，调用方需持有锁
+func (gs *GameState) addPlayer(conn Connection, protocol Protocol, opts PlayerOptions) (*Snake, string) {
+	snake := gs.createSnake(false)
+	if opts.Name != "" {
+		snake.Name = opts.Name
+		snake.nickname = opts.Name
+	}
+	snake.Name = gs.uniqueName(snake.Name)
+	if opts.Color != "" {
+		snake.Color = opts.Color
+	}
+	snake.Conn = conn
+	gs.addSnake(snake)
+	gs.recordEvent(&RecordEvent{Type: EventJoin, SnakeID: snake.ID, Name: opts.Name, Color: opts.Color})
+	token := newSessionToken()
+	gs.sessions[token] = snake.ID
+	if conn != nil {
+		gs.attach(snake, conn, protocol, token)
+	}
+	return snake, token
+}
+
+// RemoveSnake 从游戏中移除一条蛇，并停止向其连接推送状态
 func (gs *GameState) RemoveSnake(id string) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.record(EventLeave, id, nil)
+	delete(gs.clients, id)
 	// 如果蛇还存在，则将其转换为苹果
 	if snake, ok := gs.snakes[id]; ok {
-		gs.snakeToApples(snake)
+		gs.snakeToApples(snake, nil)
 	}
-	gs.broadcastState()
 }
 
-// UpdateSnakeDirection 更新蛇的移动方向
-func (gs *GameState) UpdateSnakeDirection(id string, dir Direction) {
+// UpdateSnakeDirection 将玩家的转向输入放入蛇的输入缓存，在之后的tick中依次应用
+// 方向不合法或与之前的输入相反时返回错误
+func (gs *GameState) UpdateSnakeDirection(id string, dir Direction) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
-	if snake, ok := gs.snakes[id]; ok {
-		snake.Direction = dir
+	snake, ok := gs.snakes[id]
+	if !ok || snake.Dead || snake.Frozen {
+		return nil
 	}
+	queued, err := snake.queueInput(dir)
+	if queued {
+		gs.record(EventDirection, id, &dir)
+	}
+	return err
 }
 
-// UpdateGame 更新游戏状态
+// UpdateGame 将游戏推进一个tick
 func (gs *GameState) UpdateGame() {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	start := time.Now()
+	gs.tick++
+	gs.expireDetached()
+
+	// 按配置的间隔生成AI蛇和苹果
+	if gs.tick%gs.config.Ticks(gs.config.AISpawnInterval) == 0 {
+		gs.spawnAISnake()
+	}
+	if gs.tick%gs.config.Ticks(gs.config.AppleSpawnInterval) == 0 {
+		gs.spawnApple()
+	}
 
-	// 检查并移除超过20秒的苹果
-	now := time.Now()
+	// 检查并移除超过存活时间的苹果
+	lifetime := gs.config.Ticks(gs.config.AppleLifetime)
 	var validApples []AppleInfo
 	for _, apple := range gs.apples {
-		if now.Sub(apple.CreatedAt).Seconds() < float64(gs.config.AppleLifetime) {
+		if gs.tick-apple.CreatedTick < lifetime {
 			validApples = append(validApples, apple)
+		} else {
+			gs.grid.removeApple(apple.Position)
 		}
 	}
-	if len(validApples) != len(gs.apples) {
-		gs.apples = validApples
-		gs.broadcastState()
-	}
+	gs.apples = validApples
 
 	// 更新AI蛇的方向
-	for _, snake := range gs.snakes {
-		if snake.IsAI && !snake.Dead {
-			ai := NewAIController(snake, gs.config)
-			snake.Direction = ai.DecideNextMove(gs)
-		}
-	}
+	aiStart := time.Now()
+	gs.decideAI()
+	aiElapsed := time.Since(aiStart)
 
-	// 更新所有蛇的位置
-	for _, snake := range gs.snakes {
-		if snake.Dead {
-			continue
-		}
-
-		// 移动蛇身
-		snake.Body = append([]Position{{X: snake.X, Y: snake.Y}}, snake.Body...)
-		snake.X = (snake.X + snake.Direction.X + gs.config.Cols) % gs.config.Cols
-		snake.Y = (snake.Y + snake.Direction.Y + gs.config.Rows) % gs.config.Rows
-
-		// 检查是否吃到苹果
-		for i, apple := range gs.apples {
-			if apple.Position.X == snake.X && apple.Position.Y == snake.Y {
-				gs.apples = append(gs.apples[:i], gs.apples[i+1:]...)
-				goto skipTail
-			}
-		}
-		if len(snake.Body) > 0 {
-			snake.Body = snake.Body[:len(snake.Body)-1]
-		}
-	skipTail:
-
-		// 检查自身碰撞
-		for _, segment := range snake.Body {
-			if segment.X == snake.X && segment.Y == snake.Y {
-				// 处理蛇的死亡，转换为苹果
-				gs.snakeToApples(snake)
-				goto nextSnake
-			}
-		}
-
-		// 检查与其他蛇的碰撞
-		for _, other := range gs.snakes {
-			if other == snake || other.Dead {
-				continue
-			}
-			for _, segment := range other.Body {
-				if segment.X == snake.X && segment.Y == snake.Y {
-					// 处理蛇的死亡，转换为苹果
-					gs.snakeToApples(snake)
-					goto nextSnake
-				}
-			}
-		}
-	nextSnake:
-	}
+	// 所有蛇同时移动，再根据移动后的状态处理碰撞
+	gs.moveSnakes()
 
+	gs.recordTick()
 	gs.broadcastState()
-}
 
-// broadcastState 向所有玩家广播游戏状态
-func (gs *GameState) broadcastState() {
-	state := struct {
-		Snakes      map[string]*Snake `json:"snakes"`
-		Apples      []Position        `json:"apples"`
-		Config      *GameConfig       `json:"config"`
-		DeadSnakeID string            `json:"deadSnakeId,omitempty"`
-	}{
-		Snakes: gs.snakes,
-		Apples: func() []Position {
-			positions := make([]Position, len(gs.apples))
-			for i, apple := range gs.apples {
-				positions[i] = apple.Position
-			}
-			return positions
-		}(),
-		Config: gs.config,
-	}
-
-	for _, snake := range gs.snakes {
-		if !snake.IsAI && snake.Conn != nil {
-			snake.Conn.WriteJSON(state)
-		}
-	}
+	interval := time.Duration(gs.config.UpdateInterval) * time.Millisecond
+	gs.metrics.add(time.Since(start), aiElapsed, interval)
 }
 
-// snakeToApples 将死亡的蛇转换为苹果
-func (gs *GameState) snakeToApples(snake *Snake) {
+// snakeToApples 将死亡的蛇转换为苹果，killer为撞上的蛇，没有时为nil
+// 死亡和击杀事件在下次广播时推送
+func (gs *GameState) snakeToApples(snake *Snake, killer *Snake) {
 	// 设置蛇的死亡状态
 	snake.Dead = true
 
-	// 广播死亡事件
-	deathEvent := struct {
-		Snakes      map[string]*Snake `json:"snakes"`
-		Apples      []Position        `json:"apples"`
-		Config      *GameConfig       `json:"config"`
-		DeadSnakeID string            `json:"deadSnakeId"`
-	}{
-		Snakes: gs.snakes,
-		Apples: func() []Position {
-			positions := make([]Position, len(gs.apples))
-			for i, apple := range gs.apples {
-				positions[i] = apple.Position
-			}
-			return positions
-		}(),
-		Config:      gs.config,
-		DeadSnakeID: snake.ID,
+	gs.died = append(gs.died, snake.ID)
+	death := &DeathEvent{
+		SnakeID:     snake.ID,
+		Tick:        gs.tick,
+		Length:      len(snake.Body),
+		Score:       snake.Score,
+		Kills:       snake.Kills,
+		Alive:       gs.aliveSeconds(snake),
+		RespawnTick: gs.tick + gs.config.Ticks(gs.config.RespawnCooldown),
 	}
-
-	// 向所有玩家广播死亡事件
-	for _, s := range gs.snakes {
-		if !s.IsAI && s.Conn != nil {
-			s.Conn.WriteJSON(deathEvent)
-		}
+	if c, ok := gs.clients[snake.ID]; ok {
+		c.respawn = death.RespawnTick
+	}
+	if killer != nil {
+		killer.creditKill()
+		death.KillerID = killer.ID
+		death.KillerName = killer.Name
+		gs.kills = append(gs.kills, &KillEvent{
+			Tick:       gs.tick,
+			KillerID:   killer.ID,
+			KillerName: killer.Name,
+			VictimID:   snake.ID,
+			VictimName: snake.Name,
+		})
 	}
+	gs.deaths = append(gs.deaths, death)
+	gs.reportResult(snake)
 
-	// 在蛇身体的每个位置生成苹果
+	// 从游戏中移除死亡的蛇，并在蛇身体的每个位置生成苹果
+	gs.deleteSnake(snake.ID)
 	for _, segment := range snake.Body {
-		gs.apples = append(gs.apples, AppleInfo{
-			Position:  segment,
-			CreatedAt: time.Now(),
-		})
+		gs.addApple(segment)
 	}
-	// 从游戏中移除死亡的蛇
-	delete(gs.snakes, snake.ID)
 }
 
-// spawnAISnakes 定期生成AI控制的蛇
-func (gs *GameState) spawnAISnakes() {
-	ticker := time.NewTicker(time.Duration(gs.config.AISpawnInterval) * time.Second) // 固定10秒生成一个AI
-	for range ticker.C {
-		gs.mu.Lock()
-		// 检查当前AI数量
-		aiCount := 0
-		for _, s := range gs.snakes {
-			if s.IsAI && !s.Dead {
-				aiCount++
-			}
+// addSnake 将蛇加入游戏，调用方需持有锁
+func (gs *GameState) addSnake(snake *Snake) {
+	gs.ranks++
+	snake.rank = gs.ranks
+	gs.snakes[snake.ID] = snake
+	gs.order = append(gs.order, snake)
+	gs.grid.addSnake(snake)
+}
+
+// deleteSnake 将蛇从游戏中删除，调用方需持有锁
+func (gs *GameState) deleteSnake(id string) {
+	if snake, ok := gs.snakes[id]; ok {
+		gs.grid.removeSnake(snake)
+	}
+	delete(gs.snakes, id)
+	delete(gs.detached, id)
+	for token, snakeID := range gs.sessions {
+		if snakeID == id {
+			delete(gs.sessions, token)
 		}
+	}
+	for i, snake := range gs.order {
+		if snake.ID == id {
+			gs.order = append(gs.order[:i:i], gs.order[i+1:]...)
+			break
+		}
+	}
+}
 
-		// 限制场景中的AI数量
-		if aiCount < gs.config.MaxAICount {
-			// 创建新的AI蛇
-			snake := CreateSnake(true)
-
-			// 检查生成位置是否与其他蛇重叠
-			isValidPosition := true
-			for _, existingSnake := range gs.snakes {
-				if !existingSnake.Dead {
-					// 检查头部位置
-					if snake.X == existingSnake.X && snake.Y == existingSnake.Y {
-						isValidPosition = false
-						break
-					}
-					// 检查身体位置
-					for _, segment := range existingSnake.Body {
-						if snake.X == segment.X && snake.Y == segment.Y {
-							isValidPosition = false
-							break
-						}
-					}
-				}
-			}
+// isValidSpawn 检查新蛇的头部是否与其他蛇的头部或身体重叠，调用方需持有锁
+func (gs *GameState) isValidSpawn(snake *Snake) bool {
+	return !gs.grid.occupied(Position{X: snake.X, Y: snake.Y})
+}
 
-			// 如果位置有效，则添加到游戏中
-			if isValidPosition {
-				gs.snakes[snake.ID] = snake
-				gs.broadcastState()
-			}
-		}
-		gs.mu.Unlock()
+// spawnAISnake 在AI数量未达上限时生成一条AI蛇，调用方需持有锁
+func (gs *GameState) spawnAISnake() {
+	// 限制场景中的AI数量
+	if gs.aiCount() >= gs.config.MaxAICount {
+		return
+	}
+
+	// 创建新的AI蛇，位置有效时才添加到游戏中
+	snake := gs.createSnake(true)
+	if gs.isValidSpawn(snake) {
+		gs.addSnake(snake)
 	}
 }
 
-// spawnApples 定期在随机位置生成苹果
-func (gs *GameState) spawnApples() {
-	ticker := time.NewTicker(time.Duration(gs.config.AppleSpawnInterval) * time.Second)
-	for range ticker.C {
-		gs.mu.Lock()
-		// 生成随机位置
-		x := rand.Intn(gs.config.Cols)
-		y := rand.Intn(gs.config.Rows)
-
-		// 检查该位置是否已经有苹果或蛇
-		isValidPosition := true
-		// 检查是否与现有苹果重叠
-		for _, apple := range gs.apples {
-			if apple.Position.X == x && apple.Position.Y == y {
-				isValidPosition = false
-				break
-			}
-		}
-		// 检查是否与蛇重叠
-		if isValidPosition {
-			for _, snake := range gs.snakes {
-				if !snake.Dead {
-					if snake.X == x && snake.Y == y {
-						isValidPosition = false
-						break
-					}
-					for _, segment := range snake.Body {
-						if segment.X == x && segment.Y == y {
-							isValidPosition = false
-							break
-						}
-					}
-				}
-			}
-		}
+// spawnApple 在随机位置生成一个苹果，调用方需持有锁
+func (gs *GameState) spawnApple() {
+	// 生成随机位置，有地图时只在苹果生成区域或空地上生成
+	var x, y int
+	if gs.config.Map != nil {
+		pos := gs.randomOpen(gs.config.Map.Apples)
+		x, y = pos.X, pos.Y
+	} else {
+		x = gs.rng.Intn(gs.config.Cols)
+		y = gs.rng.Intn(gs.config.Rows)
+	}
 
-		// 如果位置有效，添加苹果
-		if isValidPosition {
-			gs.apples = append(gs.apples, AppleInfo{
-				Position:  Position{X: x, Y: y},
-				CreatedAt: time.Now(),
-			})
-			gs.broadcastState()
-		}
-		gs.mu.Unlock()
+	// 检查是否与现有苹果或蛇重叠
+	pos := Position{X: x, Y: y}
+	if gs.grid.hasApple(pos) || gs.grid.occupied(pos) {
+		return
 	}
+	gs.addApple(pos)
+}
+
+// addApple 在指定位置添加一个苹果，调用方需持有锁
+func (gs *GameState) addApple(pos Position) {
+	gs.apples = append(gs.apples, AppleInfo{
+		Position:    pos,
+		CreatedTick: gs.tick,
+	})
+	gs.grid.addApple(pos)
 }
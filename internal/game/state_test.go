@@ -0,0 +1,45 @@
+package game
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSameSeedSameTicks(t *testing.T) {
+	for _, boundary := range []Boundary{BoundaryWrap, BoundaryWalls} {
+		t.Run(string(boundary), func(t *testing.T) {
+			newState := func() *GameState {
+				config := DefaultConfig()
+				config.Cols, config.Rows = 50, 50
+				config.InitialAICount, config.MaxAICount = 20, 30
+				config.Boundary = boundary
+				config.Seed = 42
+				// 不限制决策时间，避免机器繁忙时超时导致两局不同
+				config.AIDeadline = 0
+				gs, err := NewGameState(config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return gs
+			}
+			a, b := newState(), newState()
+			dirs := []Direction{{0, 1}, {1, 0}, {0, -1}, {-1, 0}}
+			var players [2]*Snake
+			for tick := 0; tick < 300; tick++ {
+				for i, gs := range []*GameState{a, b} {
+					// 两局收到完全相同的玩家输入
+					if tick%60 == 5 {
+						players[i], _, _ = gs.AddPlayer(nil, ProtocolFull, PlayerOptions{Name: fmt.Sprint("p", tick)})
+					}
+					if players[i] != nil && tick%3 == 0 {
+						gs.UpdateSnakeDirection(players[i].ID, dirs[(tick/3)%len(dirs)])
+					}
+					gs.UpdateGame()
+				}
+				if got, want := mustJSON(t, a.Snapshot()), mustJSON(t, b.Snapshot()); got != want {
+					t.Fatalf("tick %d 的快照不一致", a.Tick())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// StrategyPersonality 按性格权重为每个方向打分的内置策略
+const StrategyPersonality = "personality"
+
+// Strategy AI蛇的决策策略，每个tick根据局面为一条蛇选择移动方向
+//
+// 每条AI蛇持有自己的策略实例。策略通过只读的WorldView和按值传递的SnakeInfo了解局面，
+// 无法修改游戏；返回不合法的方向或反方向时蛇保持当前方向。
+// 需要在tick之间保存状态的策略应实现StatefulStrategy，否则快照不包含它的状态，
+// 回放跳转后的结果与录制时不一致。
+type Strategy interface {
+	Decide(world *WorldView, self SnakeInfo) Direction
+}
+
+// StatefulStrategy 在tick之间保存状态的策略
+//
+// 快照通过MarshalState保存每条蛇的策略状态，恢复时在新创建的实例上调用UnmarshalState。
+// 每个tick的决策在由当前状态复制出的实例上执行，完成后才替换蛇的实例，
+// 因此决策超时的蛇保留上一个tick的状态。两个方法都必须是确定的：
+// 恢复出的实例与原实例在相同局面下做出相同的决策。
+type StatefulStrategy interface {
+	Strategy
+	MarshalState() ([]byte, error)
+	UnmarshalState(data []byte) error
+}
+
+// StrategyFactory 为一条新的AI蛇创建策略实例
+type StrategyFactory func() Strategy
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = make(map[string]StrategyFactory)
+)
+
+// RegisterStrategy 以名称注册一种策略，通常在包的init函数中调用
+// 名称为空、factory为nil或名称重复时panic
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	if name == "" || factory == nil {
+		panic("game: 策略名称和factory不能为空")
+	}
+	if _, ok := strategies[name]; ok {
+		panic("game: 重复注册策略 " + name)
+	}
+	strategies[name] = factory
+}
+
+// NewStrategy 创建指定名称的策略实例
+func NewStrategy(name string) (Strategy, error) {
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未知的AI策略 %q，可用的策略: %v", name, Strategies())
+	}
+	return factory(), nil
+}
+
+// restoreStrategy 创建指定名称的策略实例并恢复保存的状态
+func restoreStrategy(name string, data []byte) (Strategy, error) {
+	strategy, err := NewStrategy(name)
+	if err != nil {
+		return nil, err
+	}
+	stateful, ok := strategy.(StatefulStrategy)
+	if !ok {
+		return nil, fmt.Errorf("AI策略 %q 不能恢复状态", name)
+	}
+	if err := stateful.UnmarshalState(data); err != nil {
+		return nil, fmt.Errorf("恢复AI策略 %q 的状态失败: %w", name, err)
+	}
+	return stateful, nil
+}
+
+// hasStrategy 判断策略是否已注册
+func hasStrategy(name string) bool {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	_, ok := strategies[name]
+	return ok
+}
+
+// Strategies 返回所有已注册的策略名称，按名称排序
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// personalityStrategy 内置的性格策略，每次决策都重新评估，不保存状态
+type personalityStrategy struct{}
+
+func (personalityStrategy) Decide(world *WorldView, self SnakeInfo) Direction {
+	return NewAIController(self, &world.config).DecideNextMove(world)
+}
+
+func init() {
+	RegisterStrategy(StrategyPersonality, func() Strategy { return personalityStrategy{} })
+}
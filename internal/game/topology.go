@@ -0,0 +1,74 @@
+package game
+
+// Boundary 地图边缘的处理方式
+type Boundary string
+
+const (
+	// BoundaryWrap 环形地图，从一侧边缘离开时从对侧进入
+	BoundaryWrap Boundary = "wrap"
+	// BoundaryWalls 地图边缘是墙，蛇头离开地图时死亡
+	BoundaryWalls Boundary = "walls"
+)
+
+// walls 判断地图边缘是否为墙
+func (c *GameConfig) walls() bool {
+	return c.Boundary == BoundaryWalls
+}
+
+// contains 判断位置是否在地图范围内
+func (c *GameConfig) contains(pos Position) bool {
+	return pos.X >= 0 && pos.X < c.Cols && pos.Y >= 0 && pos.Y < c.Rows
+}
+
+// step 返回从(x, y)沿dir前进一格后的位置，离开有墙的地图或进入地图中的墙时返回false
+func (c *GameConfig) step(x, y int, dir Direction) (Position, bool) {
+	pos := Position{X: x + dir.X, Y: y + dir.Y}
+	if c.walls() {
+		return pos, c.contains(pos) && !c.wall(pos)
+	}
+	pos = c.normalize(pos)
+	return pos, !c.wall(pos)
+}
+
+// normalize 将任意坐标转换为地图内的坐标：环形地图取模，有墙时限制在边缘以内
+func (c *GameConfig) normalize(pos Position) Position {
+	if c.walls() {
+		return Position{X: clamp(pos.X, 0, c.Cols-1), Y: clamp(pos.Y, 0, c.Rows-1)}
+	}
+	return Position{
+		X: (pos.X%c.Cols + c.Cols) % c.Cols,
+		Y: (pos.Y%c.Rows + c.Rows) % c.Rows,
+	}
+}
+
+// distance 计算两点之间的曼哈顿距离，环形地图上取绕行和直行中较短的一个
+func (c *GameConfig) distance(x1, y1, x2, y2 int) int {
+	dx := abs(x1 - x2)
+	dy := abs(y1 - y2)
+	if !c.walls() {
+		if c.Cols-dx < dx {
+			dx = c.Cols - dx
+		}
+		if c.Rows-dy < dy {
+			dy = c.Rows - dy
+		}
+	}
+	return dx + dy
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -14,13 +14,46 @@ type Position struct {
 
 // GameConfig 游戏配置
 type GameConfig struct {
-	Cols               int `json:"cols"`
-	Rows               int `json:"rows"`
-	InitialSnakeLength int
-	AISpawnInterval    int
-	UpdateInterval     int
-	InitialAICount     int
-	MaxAICount         int
-	AppleSpawnInterval int
-	AppleLifetime      int
+	Cols               int `json:"cols" yaml:"cols"`
+	Rows               int `json:"rows" yaml:"rows"`
+	InitialSnakeLength int `json:"initialSnakeLength" yaml:"initial_snake_length"`
+	AISpawnInterval    int `json:"aiSpawnInterval" yaml:"ai_spawn_interval"`
+	UpdateInterval     int `json:"updateInterval" yaml:"update_interval"`
+	InitialAICount     int `json:"initialAICount" yaml:"initial_ai_count"`
+	MaxAICount         int `json:"maxAICount" yaml:"max_ai_count"`
+	AppleSpawnInterval int `json:"appleSpawnInterval" yaml:"apple_spawn_interval"`
+	AppleLifetime      int `json:"appleLifetime" yaml:"apple_lifetime"`
+	// ViewportCols、ViewportRows 推送给玩家的视野大小(格)，以玩家蛇头为中心
+	ViewportCols int `json:"viewportCols" yaml:"viewport_cols"`
+	ViewportRows int `json:"viewportRows" yaml:"viewport_rows"`
+	// MinimapInterval 推送小地图的时间间隔(秒)
+	MinimapInterval int `json:"minimapInterval" yaml:"minimap_interval"`
+	// SessionGrace 玩家断线后保留其蛇等待重连的时间(秒)，为0时立即移除
+	SessionGrace int `json:"sessionGrace" yaml:"session_grace"`
+	// RespawnCooldown 玩家的蛇死亡后可以复活前需要等待的时间(秒)
+	RespawnCooldown int `json:"respawnCooldown" yaml:"respawn_cooldown"`
+	// Boundary 地图边缘的处理方式，wrap为环形地图，walls为致命的墙
+	Boundary Boundary `json:"boundary" yaml:"boundary"`
+	// MapFile 地图文件路径(ASCII或JSON)，为空时场地上没有墙
+	MapFile string `json:"-" yaml:"map"`
+	// Map 由MapFile加载的地图，随欢迎消息单独推送
+	Map *GameMap `json:"-" yaml:"-"`
+	// AIStrategy AI蛇使用的策略名称，见RegisterStrategy
+	AIStrategy string `json:"aiStrategy" yaml:"ai_strategy"`
+	// AIWorkers 并发执行AI决策的goroutine数量，为0时使用GOMAXPROCS
+	AIWorkers int `json:"-" yaml:"ai_workers"`
+	// AIDeadline 每个tick中AI决策的时间上限(毫秒)，超时的蛇保持当前方向，为0时不限制
+	AIDeadline int `json:"-" yaml:"ai_deadline"`
+	// HeadOnRule 两条蛇头对头相撞时的处理规则，both或longer
+	HeadOnRule HeadOnRule `json:"headOnRule" yaml:"head_on_rule"`
+	// LeaderboardInterval 推送排行榜的时间间隔(秒)
+	LeaderboardInterval int `json:"leaderboardInterval" yaml:"leaderboard_interval"`
+	// LeaderboardSize 排行榜包含的蛇的数量
+	LeaderboardSize int `json:"leaderboardSize" yaml:"leaderboard_size"`
+	// Seed 随机数种子，为0时使用当前时间
+	Seed int64 `json:"seed" yaml:"seed"`
+	// RecordDir 录像保存目录，为空时不录像
+	RecordDir string `json:"-" yaml:"record_dir"`
+	// SnapshotInterval 录像中写入快照的间隔(tick)
+	SnapshotInterval int `json:"-" yaml:"snapshot_interval"`
 }
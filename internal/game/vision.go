@@ -1,66 +1,53 @@
 package game
 
-// GetViewInfo 获取AI蛇的视野范围内的信息
-func (s *Snake) GetViewInfo(gameState *GameState) ViewInfo {
-	// 创建视野信息结构，增加威胁等级评估
-	view := ViewInfo{
-		Center:    Position{X: s.X, Y: s.Y},
-		Food:      make([]Position, 0),
-		Snakes:    make([]*Snake, 0),
-		Obstacles: make([]Position, 0),
+// isInView 判断一个位置是否在视野范围内
+func isInView(config *GameConfig, x, y, minX, maxX, minY, maxY int) bool {
+	// 有墙时视野不会绕到地图另一侧
+	if config.walls() {
+		return x >= minX && x <= maxX && y >= minY && y <= maxY
 	}
+	// 处理地图边界循环的情况
+	return inRange(x, minX, maxX, config.Cols) && inRange(y, minY, maxY, config.Rows)
+}
 
-	// 计算视野范围
-	minX := s.X - ViewWidth/2
-	maxX := s.X + ViewWidth/2
-	minY := s.Y - ViewHeight/2
-	maxY := s.Y + ViewHeight/2
+// inRange 判断坐标v是否落在环形轴上的[min, max]区间内，size为轴长度
+func inRange(v, min, max, size int) bool {
+	// 视野覆盖整条轴时总是可见（小地图的情况）
+	if max-min+1 >= size {
+		return true
+	}
 
-	// 获取视野范围内的食物
-	for _, food := range gameState.apples {
-		if isInView(food.Position.X, food.Position.Y, minX, maxX, minY, maxY) {
-			view.Food = append(view.Food, food.Position)
-		}
+	// 将坐标转换到合法范围内
+	v = (v%size + size) % size
+	min = (min%size + size) % size
+	max = (max%size + size) % size
+
+	// 处理跨越边界的情况
+	if min > max {
+		return v >= min || v <= max
 	}
+	return v >= min && v <= max
+}
 
-	// 获取视野范围内的其他蛇
-	for _, snake := range gameState.snakes {
-		if snake.ID != s.ID && !snake.Dead {
-			if isInView(snake.X, snake.Y, minX, maxX, minY, maxY) {
-				view.Snakes = append(view.Snakes, snake)
-			}
-			// 将其他蛇的身体部分作为障碍物
-			for _, pos := range snake.Body {
-				if isInView(pos.X, pos.Y, minX, maxX, minY, maxY) {
-					view.Obstacles = append(view.Obstacles, pos)
-				}
-			}
+// forEachCell 按行遍历[minX, maxX]x[minY, maxY]范围内的每个格子
+// 环形地图上范围可以跨越边缘，超出整条轴时每个格子只遍历一次；有墙时只遍历地图内的部分
+func (c *GameConfig) forEachCell(minX, maxX, minY, maxY int, fn func(pos Position)) {
+	minX, maxX = c.axis(minX, maxX, c.Cols)
+	minY, maxY = c.axis(minY, maxY, c.Rows)
+	for y := minY; y <= maxY; y++ {
+		for x := minX; x <= maxX; x++ {
+			fn(c.normalize(Position{X: x, Y: y}))
 		}
 	}
-
-	return view
 }
 
-// isInView 判断一个位置是否在视野范围内
-func isInView(x, y, minX, maxX, minY, maxY int) bool {
-	// 处理地图边界循环的情况
-	config := DefaultConfig()
-
-	// 将坐标转换到合法范围内
-	x = (x + config.Cols) % config.Cols
-	y = (y + config.Rows) % config.Rows
-	minX = (minX + config.Cols) % config.Cols
-	maxX = (maxX + config.Cols) % config.Cols
-	minY = (minY + config.Rows) % config.Rows
-	maxY = (maxY + config.Rows) % config.Rows
-
-	// 处理跨越边界的情况
-	if minX > maxX {
-		return x >= minX || x <= maxX
+// axis 返回一条轴上需要遍历的范围，size为轴长度
+func (c *GameConfig) axis(min, max, size int) (int, int) {
+	if c.walls() {
+		return clamp(min, 0, size), clamp(max, -1, size-1)
 	}
-	if minY > maxY {
-		return y >= minY || y <= maxY
+	if max-min+1 > size {
+		return 0, size - 1
 	}
-
-	return x >= minX && x <= maxX && y >= minY && y <= maxY
+	return min, max
 }
@@ -0,0 +1,205 @@
+package game
+
+import "sort"
+
+// SnakeInfo 蛇的只读概要，按值传递，修改它不会影响游戏
+type SnakeInfo struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	IsAI        bool            `json:"isAI"`
+	X           int             `json:"x"`
+	Y           int             `json:"y"`
+	Direction   Direction       `json:"direction"`
+	Length      int             `json:"length"`
+	Frozen      bool            `json:"frozen,omitempty"`
+	Personality PersonalityType `json:"personality"`
+	Score       int             `json:"score"`
+}
+
+// info 返回蛇当前状态的概要
+func (s *Snake) info() SnakeInfo {
+	return SnakeInfo{
+		ID:          s.ID,
+		Name:        s.Name,
+		IsAI:        s.IsAI,
+		X:           s.X,
+		Y:           s.Y,
+		Direction:   s.Direction,
+		Length:      len(s.Body),
+		Frozen:      s.Frozen,
+		Personality: s.Personality,
+		Score:       s.Score,
+	}
+}
+
+// WorldView 某个tick开始、AI决策之前的只读局面
+//
+// 每个tick生成一次，由所有AI策略共享，也可以通过GameState.World交给外部的机器人和插件。
+// 生成后不再修改，可以在不持有游戏锁的情况下在任意goroutine中并发读取。
+type WorldView struct {
+	tick   uint64
+	config GameConfig
+	snakes []SnakeInfo // 存活的蛇，按加入游戏的顺序排列
+	index  map[string]int
+	apples []Position
+	counts []int     // 每格上的苹果数量
+	heads  cellIndex // 每格上蛇头所在的蛇
+	bodies cellIndex // 每格上身体所在的蛇
+}
+
+// cellIndex 每个格子上的蛇在WorldView.snakes中的下标，按格子顺序紧凑存储
+type cellIndex struct {
+	start  []int32
+	snakes []int32
+}
+
+// newCellIndex 将网格中每格的蛇列表转换为下标
+func newCellIndex(cells [][]*Snake, index map[*Snake]int32) cellIndex {
+	ci := cellIndex{start: make([]int32, len(cells)+1)}
+	for i, snakes := range cells {
+		ci.start[i] = int32(len(ci.snakes))
+		for _, snake := range snakes {
+			ci.snakes = append(ci.snakes, index[snake])
+		}
+	}
+	ci.start[len(cells)] = int32(len(ci.snakes))
+	return ci
+}
+
+// at 返回第i个格子上的蛇的下标
+func (ci cellIndex) at(i int) []int32 {
+	return ci.snakes[ci.start[i]:ci.start[i+1]]
+}
+
+// newWorldView 生成当前局面的只读快照，调用方需持有锁
+func (gs *GameState) newWorldView() *WorldView {
+	w := &WorldView{
+		tick:   gs.tick,
+		config: *gs.config,
+		snakes: make([]SnakeInfo, len(gs.order)),
+		index:  make(map[string]int, len(gs.order)),
+		apples: make([]Position, len(gs.apples)),
+		counts: append([]int(nil), gs.grid.apples...),
+	}
+	index := make(map[*Snake]int32, len(gs.order))
+	for i, snake := range gs.order {
+		w.snakes[i] = snake.info()
+		w.index[snake.ID] = i
+		index[snake] = int32(i)
+	}
+	for i, apple := range gs.apples {
+		w.apples[i] = apple.Position
+	}
+	w.heads = newCellIndex(gs.grid.heads, index)
+	w.bodies = newCellIndex(gs.grid.bodies, index)
+	return w
+}
+
+// World 返回最近一个tick中AI决策时使用的只读局面，还没有推进过tick时返回当前局面
+func (gs *GameState) World() *WorldView {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	if gs.world == nil {
+		gs.world = gs.newWorldView()
+	}
+	return gs.world
+}
+
+// Tick 返回局面所在的tick
+func (w *WorldView) Tick() uint64 {
+	return w.tick
+}
+
+// Config 返回游戏配置的副本
+func (w *WorldView) Config() GameConfig {
+	return w.config
+}
+
+// Snakes 返回所有存活的蛇，按加入游戏的顺序排列
+func (w *WorldView) Snakes() []SnakeInfo {
+	return append([]SnakeInfo(nil), w.snakes...)
+}
+
+// Snake 返回指定ID的蛇
+func (w *WorldView) Snake(id string) (SnakeInfo, bool) {
+	i, ok := w.index[id]
+	if !ok {
+		return SnakeInfo{}, false
+	}
+	return w.snakes[i], true
+}
+
+// Apples 返回所有苹果的位置
+func (w *WorldView) Apples() []Position {
+	return append([]Position(nil), w.apples...)
+}
+
+// Occupied 判断格子上是否有蛇的头或身体
+func (w *WorldView) Occupied(pos Position) bool {
+	i := w.cell(pos)
+	return len(w.heads.at(i)) > 0 || len(w.bodies.at(i)) > 0
+}
+
+// Wall 判断格子是否是地图中的墙
+func (w *WorldView) Wall(pos Position) bool {
+	return w.config.wall(pos)
+}
+
+// Step 返回从pos沿dir前进一格后的位置，撞墙时返回false
+func (w *WorldView) Step(pos Position, dir Direction) (Position, bool) {
+	return w.config.step(pos.X, pos.Y, dir)
+}
+
+// Distance 返回两点之间的曼哈顿距离，环形地图上考虑绕行
+func (w *WorldView) Distance(a, b Position) int {
+	return w.config.distance(a.X, a.Y, b.X, b.Y)
+}
+
+// View 返回以蛇头为中心、ViewWidth x ViewHeight 范围内的食物、其他蛇和障碍物
+func (w *WorldView) View(self SnakeInfo) ViewInfo {
+	view := ViewInfo{
+		Center:    Position{X: self.X, Y: self.Y},
+		Food:      make([]Position, 0),
+		Snakes:    make([]SnakeInfo, 0),
+		Obstacles: make([]Position, 0),
+	}
+	selfIndex := int32(-1)
+	if i, ok := w.index[self.ID]; ok {
+		selfIndex = int32(i)
+	}
+
+	// 按行遍历视野内的格子，收集食物、其他蛇的头部和身体
+	var others []int32
+	minX := self.X - ViewWidth/2
+	maxX := self.X + ViewWidth/2
+	minY := self.Y - ViewHeight/2
+	maxY := self.Y + ViewHeight/2
+	w.config.forEachCell(minX, maxX, minY, maxY, func(pos Position) {
+		i := w.cell(pos)
+		for n := w.counts[i]; n > 0; n-- {
+			view.Food = append(view.Food, pos)
+		}
+		for _, snake := range w.heads.at(i) {
+			if snake != selfIndex {
+				others = append(others, snake)
+			}
+		}
+		// 将其他蛇的身体部分作为障碍物
+		for _, snake := range w.bodies.at(i) {
+			if snake != selfIndex {
+				view.Obstacles = append(view.Obstacles, pos)
+			}
+		}
+	})
+	// 其他蛇按加入游戏的顺序排列，与格子中记录的先后无关
+	sort.Slice(others, func(i, j int) bool { return others[i] < others[j] })
+	for _, snake := range others {
+		view.Snakes = append(view.Snakes, w.snakes[snake])
+	}
+	return view
+}
+
+// cell 返回位置在网格中的下标
+func (w *WorldView) cell(pos Position) int {
+	return pos.Y*w.config.Cols + pos.X
+}
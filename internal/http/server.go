@@ -3,21 +3,27 @@ package http
 
 import (
 	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"snakesol/internal/game"
 	"snakesol/internal/network"
+	"snakesol/internal/room"
+	"snakesol/internal/stats"
 )
 
 // Config HTTP服务器配置
 type Config struct {
-	Addr         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Addr         string        `yaml:"addr"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
 }
 
 // DefaultConfig 返回默认配置
@@ -30,20 +36,48 @@ func DefaultConfig() *Config {
 	}
 }
 
+// Validate 检查配置是否合理
+func (c *Config) Validate() error {
+	switch {
+	case c.Addr == "":
+		return errors.New("addr 不能为空")
+	case c.ReadTimeout < 0:
+		return fmt.Errorf("read_timeout 不能为负数，当前为 %s", c.ReadTimeout)
+	case c.WriteTimeout < 0:
+		return fmt.Errorf("write_timeout 不能为负数，当前为 %s", c.WriteTimeout)
+	case c.IdleTimeout < 0:
+		return fmt.Errorf("idle_timeout 不能为负数，当前为 %s", c.IdleTimeout)
+	}
+	return nil
+}
+
 // Server HTTP服务器
 type Server struct {
 	config    *Config
-	gameState *game.GameState
-	wsServer  *network.WSServer
+	rooms     *room.Manager
+	stats     stats.Store
+	topLimit  int
+	wsHandler http.HandlerFunc
 	staticFS  embed.FS
 }
 
-// NewServer 创建HTTP服务器
-func NewServer(config *Config, gameState *game.GameState, staticFS embed.FS) *Server {
+// NewServer 创建游戏HTTP服务器，topLimit为历史排行榜一次最多返回的玩家数
+func NewServer(config *Config, rooms *room.Manager, ws *network.WSServer, store stats.Store, topLimit int, staticFS embed.FS) *Server {
+	return &Server{
+		config:    config,
+		rooms:     rooms,
+		stats:     store,
+		topLimit:  topLimit,
+		wsHandler: ws.HandleConnection,
+		staticFS:  staticFS,
+	}
+}
+
+// NewReplayServer 创建回放录像的HTTP服务器，浏览器客户端通过同样的/ws观看录像
+func NewReplayServer(config *Config, replay *network.ReplayServer, staticFS embed.FS) *Server {
 	return &Server{
 		config:    config,
-		gameState: gameState,
-		wsServer:  network.NewWSServer(gameState),
+		wsHandler: replay.HandleConnection,
 		staticFS:  staticFS,
 	}
 }
@@ -63,7 +97,18 @@ func (s *Server) Start() error {
 	}))
 
 	// 设置WebSocket路由
-	http.HandleFunc("/ws", s.wsServer.HandleConnection)
+	http.HandleFunc("/ws", s.wsHandler)
+
+	// 设置大厅API
+	if s.rooms != nil {
+		http.HandleFunc("/api/rooms", s.handleRooms)
+	}
+
+	// 设置玩家统计API
+	if s.stats != nil {
+		http.HandleFunc("/api/stats/", s.handleStats)
+		http.HandleFunc("/api/leaderboard", s.handleLeaderboard)
+	}
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -76,3 +121,74 @@ func (s *Server) Start() error {
 	log.Printf("游戏服务器启动在 %s", s.config.Addr)
 	return server.ListenAndServe()
 }
+
+// handleRooms 返回所有房间及其玩家和AI数量
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.rooms.List()); err != nil {
+		log.Println("写入房间列表失败:", err)
+	}
+}
+
+// handleStats 返回 /api/stats/<昵称> 的历史统计
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/stats/")
+	if name == "" {
+		http.Error(w, "缺少玩家昵称", http.StatusBadRequest)
+		return
+	}
+	playerStats, err := s.stats.Get(name)
+	if errors.Is(err, stats.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("读取玩家 %s 的统计失败: %v", name, err)
+		http.Error(w, "读取统计失败", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, playerStats)
+}
+
+// handleLeaderboard 返回单局最高分最高的玩家，limit参数指定数量，默认10
+func (s *Server) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit 必须是正整数", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	if limit > s.topLimit {
+		limit = s.topLimit
+	}
+	top, err := s.stats.Top(limit)
+	if err != nil {
+		log.Println("读取历史排行榜失败:", err)
+		http.Error(w, "读取排行榜失败", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, top)
+}
+
+// writeJSON 将v编码为JSON响应
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("写入响应失败:", err)
+	}
+}
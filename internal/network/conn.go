@@ -0,0 +1,348 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"snakesol/internal/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// DropPolicy 发送队列已满时的处理策略
+type DropPolicy string
+
+const (
+	// DropCoalesce 用新消息替代队列中尚未发送的状态消息，只保留最新的状态
+	DropCoalesce DropPolicy = "coalesce"
+	// DropNewest 丢弃新的消息
+	DropNewest DropPolicy = "drop"
+	// DropDisconnect 立即断开连接
+	DropDisconnect DropPolicy = "disconnect"
+)
+
+var (
+	// ErrConnClosed 连接已关闭
+	ErrConnClosed = errors.New("连接已关闭")
+	// ErrQueueFull 发送队列已满，消息被丢弃
+	ErrQueueFull = errors.New("发送队列已满")
+	// ErrTooSlow 客户端落后太多，连接已断开
+	ErrTooSlow = errors.New("客户端接收过慢")
+	// ErrRateLimited 客户端持续超过消息速率限制，连接已断开
+	ErrRateLimited = errors.New("客户端发送消息过快")
+)
+
+// Config WebSocket连接配置
+type Config struct {
+	SendQueueSize  int           `yaml:"send_queue_size"`
+	DropPolicy     DropPolicy    `yaml:"drop_policy"`
+	MaxDropped     int           `yaml:"max_dropped"`
+	WriteTimeout   time.Duration `yaml:"write_timeout"`
+	PingInterval   time.Duration `yaml:"ping_interval"`
+	PongTimeout    time.Duration `yaml:"pong_timeout"`
+	MaxMessageSize int64         `yaml:"max_message_size"`
+	MessageRate    float64       `yaml:"message_rate"`
+	MessageBurst   int           `yaml:"message_burst"`
+	MaxConnsPerIP  int           `yaml:"max_conns_per_ip"`
+	MaxPlayers     int           `yaml:"max_players"`
+	AllowedOrigins []string      `yaml:"allowed_origins"`
+}
+
+// DefaultConfig 返回默认的WebSocket连接配置
+func DefaultConfig() *Config {
+	return &Config{
+		// 每个连接最多缓存的待发送消息数
+		SendQueueSize: 16,
+		// 队列满时只保留最新的状态
+		DropPolicy: DropCoalesce,
+		// 队列清空前累计丢弃超过这个数量的消息时断开连接
+		MaxDropped: 64,
+		// 单条消息的写超时
+		WriteTimeout: 5 * time.Second,
+		// 向客户端发送ping的间隔
+		PingInterval: 10 * time.Second,
+		// 超过这段时间没有收到pong或任何消息时断开连接
+		PongTimeout: 30 * time.Second,
+		// 客户端消息的最大字节数
+		MaxMessageSize: 1024,
+		// 每个连接每秒允许发送的消息数，0表示不限制
+		MessageRate: 20,
+		// 允许短时间内连续发送的消息数
+		MessageBurst: 40,
+		// 每个IP最多同时建立的连接数，0表示不限制
+		MaxConnsPerIP: 8,
+		// 全服同时在线的玩家数上限，0表示不限制
+		MaxPlayers: 500,
+		// 允许的Origin，为空时只允许同源连接
+		AllowedOrigins: []string{},
+	}
+}
+
+// Validate 检查配置是否合理
+func (c *Config) Validate() error {
+	switch {
+	case c.SendQueueSize <= 0:
+		return fmt.Errorf("send_queue_size 必须大于0，当前为 %d", c.SendQueueSize)
+	case c.DropPolicy != DropCoalesce && c.DropPolicy != DropNewest && c.DropPolicy != DropDisconnect:
+		return fmt.Errorf("drop_policy 只能是 coalesce、drop 或 disconnect，当前为 %q", c.DropPolicy)
+	case c.MaxDropped < 0:
+		return fmt.Errorf("max_dropped 不能为负数，当前为 %d", c.MaxDropped)
+	case c.WriteTimeout <= 0:
+		return fmt.Errorf("write_timeout 必须大于0，当前为 %s", c.WriteTimeout)
+	case c.PingInterval <= 0:
+		return fmt.Errorf("ping_interval 必须大于0，当前为 %s", c.PingInterval)
+	case c.PongTimeout <= c.PingInterval:
+		return fmt.Errorf("pong_timeout(%s) 必须大于 ping_interval(%s)", c.PongTimeout, c.PingInterval)
+	case c.MaxMessageSize <= 0:
+		return fmt.Errorf("max_message_size 必须大于0，当前为 %d", c.MaxMessageSize)
+	case c.MessageRate < 0:
+		return fmt.Errorf("message_rate 不能为负数，当前为 %g", c.MessageRate)
+	case c.MessageRate > 0 && c.MessageBurst < 1:
+		return fmt.Errorf("message_burst 必须至少为1，当前为 %d", c.MessageBurst)
+	case c.MaxConnsPerIP < 0:
+		return fmt.Errorf("max_conns_per_ip 不能为负数，当前为 %d", c.MaxConnsPerIP)
+	case c.MaxPlayers < 0:
+		return fmt.Errorf("max_players 不能为负数，当前为 %d", c.MaxPlayers)
+	}
+	return nil
+}
+
+// WSConnection 包装websocket.Conn以实现game.Connection接口
+//
+// WriteJSON只把消息放入有界队列，由独立的写goroutine发送，
+// 因此游戏循环在持有锁时推送状态不会被慢速客户端阻塞。
+// 关闭帧和底层连接的关闭同样只在写goroutine中进行，Close只标记连接已关闭。
+// 写goroutine同时定期发送ping，超过PongTimeout没有收到pong或任何消息时ReadJSON返回错误。
+type WSConnection struct {
+	conn   *websocket.Conn
+	config *Config
+
+	mu      sync.Mutex
+	queue   []interface{}
+	dropped int // 队列上次清空以来丢弃的消息数
+
+	wake      chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// 只在读goroutine中使用
+	limiter *rateLimiter // 为nil时不限制
+	limited int          // 连续超过速率限制的消息数
+}
+
+// newWSConnection 创建连接，设置读限制和读超时，并启动写goroutine
+func newWSConnection(conn *websocket.Conn, config *Config) *WSConnection {
+	c := &WSConnection{
+		conn:   conn,
+		config: config,
+		queue:  make([]interface{}, 0, config.SendQueueSize),
+		wake:   make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	if config.MessageRate > 0 {
+		c.limiter = newRateLimiter(config.MessageRate, config.MessageBurst)
+	}
+	conn.SetReadLimit(config.MaxMessageSize)
+	c.extendReadDeadline()
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+	go c.writeLoop()
+	return c
+}
+
+// extendReadDeadline 收到客户端的消息或pong后延长读超时
+func (c *WSConnection) extendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.config.PongTimeout))
+}
+
+// WriteJSON 实现game.Connection接口，将消息放入发送队列后立即返回
+// 消息在写goroutine中包装为带类型的信封后发送
+func (c *WSConnection) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.isClosed() {
+		return ErrConnClosed
+	}
+
+	if len(c.queue) >= c.config.SendQueueSize {
+		if err := c.overflow(v); err != nil {
+			return err
+		}
+	}
+	c.queue = append(c.queue, v)
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// overflow 按配置的策略处理已满的队列，返回nil时可以继续放入v，调用方需持有锁
+func (c *WSConnection) overflow(v interface{}) error {
+	if c.config.DropPolicy == DropDisconnect {
+		c.Close()
+		return ErrTooSlow
+	}
+	if c.config.DropPolicy == DropCoalesce {
+		// 新的状态替代队列中所有的状态；其他消息只替代最早的一条状态，
+		// 队列中没有状态时才丢弃新消息
+		limit := 1
+		if isState(v) {
+			limit = len(c.queue)
+		}
+		c.evictStates(limit)
+	}
+
+	var err error
+	if len(c.queue) >= c.config.SendQueueSize {
+		c.dropped++
+		err = ErrQueueFull
+	}
+	if c.dropped > c.config.MaxDropped {
+		c.Close()
+		return ErrTooSlow
+	}
+	return err
+}
+
+// evictStates 从队列中移除最早的至多limit条状态推送，调用方需持有锁
+func (c *WSConnection) evictStates(limit int) {
+	kept := c.queue[:0]
+	for _, queued := range c.queue {
+		if limit > 0 && isState(queued) {
+			limit--
+			continue
+		}
+		kept = append(kept, queued)
+	}
+	for i := len(kept); i < len(c.queue); i++ {
+		c.queue[i] = nil
+	}
+	c.dropped += len(c.queue) - len(kept)
+	c.queue = kept
+}
+
+// isState 判断消息是否为可被更新的状态替代的状态推送
+// 被丢弃的增量会造成序号不连续，客户端会自动请求重新同步
+func isState(v interface{}) bool {
+	switch v.(type) {
+	case *game.StateMessage, *game.DeltaMessage:
+		return true
+	}
+	return false
+}
+
+// writeLoop 依次发送队列中的消息并定期发送ping，写入失败或超时时关闭连接
+// 连接被标记为关闭后发送关闭帧并关闭底层连接
+func (c *WSConnection) writeLoop() {
+	ping := time.NewTicker(c.config.PingInterval)
+	defer ping.Stop()
+	defer c.shutdown()
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.config.WriteTimeout)); err != nil {
+				c.fail(err)
+				return
+			}
+			continue
+		case <-c.wake:
+		}
+		for {
+			c.mu.Lock()
+			if c.isClosed() {
+				c.mu.Unlock()
+				return
+			}
+			if len(c.queue) == 0 {
+				c.dropped = 0
+				c.mu.Unlock()
+				break
+			}
+			v := c.queue[0]
+			c.queue[0] = nil
+			c.queue = c.queue[1:]
+			c.mu.Unlock()
+
+			msg, err := envelope(v)
+			if err != nil {
+				log.Println("编码消息失败:", err)
+				continue
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				c.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// fail 在写入出错时关闭连接，连接已经关闭时不再记录日志
+func (c *WSConnection) fail(err error) {
+	if !c.isClosed() {
+		log.Println("发送消息失败，断开连接:", err)
+		c.Close()
+	}
+}
+
+// isClosed 判断连接是否已被标记为关闭
+func (c *WSConnection) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown 在写goroutine退出时尽量发送关闭帧，然后关闭底层连接
+func (c *WSConnection) shutdown() {
+	c.Close()
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	c.conn.Close()
+}
+
+// ReadJSON 实现game.Connection接口，每收到一条消息都会延长读超时
+func (c *WSConnection) ReadJSON(v interface{}) error {
+	if err := c.conn.ReadJSON(v); err != nil {
+		return err
+	}
+	c.extendReadDeadline()
+	return nil
+}
+
+// throttle 按令牌桶检查客户端消息的速率，返回false时应丢弃刚读到的消息
+// 连续一段时间超过限制时第一次回复error消息，连续超限的消息数超过message_burst时断开连接
+func (c *WSConnection) throttle() (bool, error) {
+	if c.limiter == nil || c.limiter.allow(time.Now()) {
+		c.limited = 0
+		return true, nil
+	}
+	c.limited++
+	if c.limited == 1 {
+		c.WriteJSON(NewError(ErrCodeRateLimited, "发送消息过快，消息已被丢弃"))
+	}
+	if c.limited > c.config.MessageBurst {
+		c.Close()
+		return false, ErrRateLimited
+	}
+	return false, nil
+}
+
+// Close 实现game.Connection接口，标记连接已关闭并立即返回，可以重复调用
+// 写goroutine随后发送关闭帧并关闭底层连接，正在进行的写入最多等待write_timeout
+func (c *WSConnection) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
@@ -0,0 +1,127 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"snakesol/internal/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// queuedConnection 创建不启动写goroutine的连接，消息只进入队列
+func queuedConnection(size int) *WSConnection {
+	config := DefaultConfig()
+	config.SendQueueSize = size
+	config.DropPolicy = DropCoalesce
+	config.MaxDropped = 100
+	return &WSConnection{
+		config: config,
+		queue:  make([]interface{}, 0, size),
+		wake:   make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+}
+
+func TestCoalesceOverflow(t *testing.T) {
+	s1, s2, s3 := &game.StateMessage{Tick: 1}, &game.StateMessage{Tick: 2}, &game.StateMessage{Tick: 3}
+	kill := &game.KillEvent{Tick: 2}
+	board := &game.LeaderboardMessage{Tick: 2}
+
+	tests := []struct {
+		name    string
+		queued  []interface{}
+		v       interface{}
+		want    []interface{}
+		wantErr error
+	}{
+		{"新状态替代所有状态", []interface{}{s1, kill, s2}, s3, []interface{}{kill, s3}, nil},
+		{"事件替代最早的状态", []interface{}{s1, kill, s2}, board, []interface{}{kill, s2, board}, nil},
+		{"没有状态时丢弃新事件", []interface{}{kill, board, kill}, board, []interface{}{kill, board, kill}, ErrQueueFull},
+		{"没有状态时丢弃新状态", []interface{}{kill, board, kill}, s3, []interface{}{kill, board, kill}, ErrQueueFull},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := queuedConnection(len(tt.queued))
+			c.queue = append(c.queue, tt.queued...)
+			if err := c.WriteJSON(tt.v); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WriteJSON() = %v，期望 %v", err, tt.wantErr)
+			}
+			if len(c.queue) != len(tt.want) {
+				t.Fatalf("队列为 %v，期望 %v", c.queue, tt.want)
+			}
+			for i := range tt.want {
+				if c.queue[i] != tt.want[i] {
+					t.Fatalf("队列第%d条为 %v，期望 %v", i, c.queue[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOverflowCloseDoesNotBlock(t *testing.T) {
+	config := DefaultConfig()
+	config.SendQueueSize = 4
+	config.DropPolicy = DropDisconnect
+	config.WriteTimeout = 2 * time.Second
+
+	conns := make(chan *WSConnection, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conns <- newWSConnection(conn, config)
+	}))
+	defer server.Close()
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	c := <-conns
+
+	// 客户端从不读取，写goroutine最终阻塞在TCP写入上，队列随之填满
+	big := &Message{Type: TypeState, Payload: json.RawMessage(`"` + strings.Repeat("x", 1<<20) + `"`)}
+	queued := func() int {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.queue)
+	}
+	stuck := time.Now()
+	for time.Since(stuck) < 300*time.Millisecond {
+		if queued() < config.SendQueueSize {
+			if err := c.WriteJSON(big); err != nil {
+				t.Fatal(err)
+			}
+			stuck = time.Now()
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// 队列已满时断开连接，不能等待阻塞的写goroutine
+	start := time.Now()
+	if err := c.WriteJSON(big); err != ErrTooSlow {
+		t.Fatalf("WriteJSON() = %v，期望 %v", err, ErrTooSlow)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("WriteJSON 耗时 %s，游戏循环被阻塞", elapsed)
+	}
+
+	// 写goroutine的写入超时后关闭底层连接
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := client.ReadMessage(); err != nil {
+			if ne, ok := err.(interface{ Timeout() bool }); ok && ne.Timeout() {
+				t.Fatal("服务器没有关闭连接")
+			}
+			break
+		}
+	}
+}
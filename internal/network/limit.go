@@ -0,0 +1,124 @@
+package network
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter 令牌桶，限制单个连接发送消息的速率
+type rateLimiter struct {
+	rate   float64 // 每秒补充的令牌数
+	burst  float64 // 桶的容量
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 创建装满令牌的令牌桶
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// allow 取出一个令牌，令牌不足时返回false
+func (l *rateLimiter) allow(now time.Time) bool {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// connLimiter 统计每个IP的连接数和全服的玩家数
+type connLimiter struct {
+	maxPerIP   int
+	maxPlayers int
+
+	mu      sync.Mutex
+	perIP   map[string]int
+	players int
+}
+
+func newConnLimiter(maxPerIP, maxPlayers int) *connLimiter {
+	return &connLimiter{maxPerIP: maxPerIP, maxPlayers: maxPlayers, perIP: make(map[string]int)}
+}
+
+// acquireIP 为IP占用一个连接名额，超过上限时返回false
+func (l *connLimiter) acquireIP(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxPerIP > 0 && l.perIP[ip] >= l.maxPerIP {
+		return false
+	}
+	l.perIP[ip]++
+	return true
+}
+
+// releaseIP 释放IP的一个连接名额
+func (l *connLimiter) releaseIP(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perIP[ip]--; l.perIP[ip] <= 0 {
+		delete(l.perIP, ip)
+	}
+}
+
+// acquirePlayer 占用一个玩家名额，服务器已满时返回false
+func (l *connLimiter) acquirePlayer() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.maxPlayers > 0 && l.players >= l.maxPlayers {
+		return false
+	}
+	l.players++
+	return true
+}
+
+// releasePlayer 释放一个玩家名额
+func (l *connLimiter) releasePlayer() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.players--
+}
+
+// remoteIP 返回请求的来源IP，不信任X-Forwarded-For等可伪造的请求头
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkOrigin 根据允许的来源列表创建CheckOrigin函数
+// 列表为空时只允许同源连接，包含"*"时允许所有来源
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		// 交给websocket.Upgrader使用默认的同源检查
+		return nil
+	}
+	for _, origin := range allowed {
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// 非浏览器客户端不带Origin
+			return true
+		}
+		for _, allowedOrigin := range allowed {
+			if strings.EqualFold(origin, allowedOrigin) {
+				return true
+			}
+		}
+		return false
+	}
+}
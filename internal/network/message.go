@@ -0,0 +1,147 @@
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"snakesol/internal/game"
+)
+
+// ProtocolVersion 消息协议版本，消息格式发生不兼容的变化时递增
+const ProtocolVersion = 1
+
+// ServerVersion 服务端版本，构建时可通过 -ldflags "-X snakesol/internal/network.ServerVersion=..." 设置
+var ServerVersion = "dev"
+
+// 服务端发送的消息类型
+const (
+	TypeWelcome     = "welcome"
+	TypeState       = "state"
+	TypeDelta       = "delta"
+	TypeDeath       = "death"
+	TypeKill        = "kill"
+	TypeLeaderboard = "leaderboard"
+	TypeError       = "error"
+)
+
+// 客户端发送的消息类型
+const (
+	TypeJoin      = "join"
+	TypeDirection = "direction"
+	TypeResync    = "resync"
+	TypeRespawn   = "respawn"
+	TypeSpectate  = "spectate"
+	TypeReplay    = "replay"
+)
+
+// 错误消息的错误码
+const (
+	ErrCodeBadMessage      = "bad_message"
+	ErrCodeUnknownType     = "unknown_type"
+	ErrCodeJoinRequired    = "join_required"
+	ErrCodeInvalidJoin     = "invalid_join"
+	ErrCodeInvalidInput    = "invalid_input"
+	ErrCodeUnknownSnake    = "unknown_snake"
+	ErrCodeCannotRespawn   = "cannot_respawn"
+	ErrCodeRateLimited     = "rate_limited"
+	ErrCodeServerFull      = "server_full"
+	ErrCodeRoomUnavailable = "room_unavailable"
+)
+
+// Message 客户端和服务端之间所有WebSocket消息的信封
+type Message struct {
+	Type    string          `json:"type"`
+	V       int             `json:"v,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// JoinPayload 客户端连接后发送的第一条消息
+type JoinPayload struct {
+	// Name 昵称，为空时随机生成，与场上其他蛇重名时自动追加编号
+	Name string `json:"name"`
+	// Color 蛇的颜色，#RRGGBB格式，为空时随机选择
+	Color string `json:"color"`
+	// Session 断线前收到的会话令牌，有效时接管原来的蛇
+	Session string `json:"session"`
+}
+
+// WelcomePayload 玩家加入、重连成功或观众连接后收到的第一条消息
+// 玩家的消息包含会话令牌和自己的蛇，观众的消息包含观众ID和跟随的蛇
+type WelcomePayload struct {
+	Version     int              `json:"version"`
+	Server      string           `json:"server"`
+	Token       string           `json:"token,omitempty"`
+	SnakeID     string           `json:"snakeId,omitempty"`
+	Name        string           `json:"name,omitempty"`
+	Color       string           `json:"color,omitempty"`
+	SpectatorID string           `json:"spectatorId,omitempty"`
+	Follow      string           `json:"follow,omitempty"`
+	Config      *game.GameConfig `json:"config"`
+	Map         *game.GameMap    `json:"map,omitempty"`
+}
+
+// SpectatePayload 观众切换视角，follow不为空时跟随该蛇，否则将视野中心移动到camera
+type SpectatePayload struct {
+	Follow string         `json:"follow"`
+	Camera *game.Position `json:"camera"`
+}
+
+// ErrorPayload 错误消息
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewMessage 将payload编码后包装为当前协议版本的消息
+func NewMessage(msgType string, payload interface{}) (*Message, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Type: msgType, V: ProtocolVersion, Payload: data}, nil
+}
+
+// NewError 创建错误消息
+func NewError(code, message string) *Message {
+	msg, _ := NewMessage(TypeError, ErrorPayload{Code: code, Message: message})
+	return msg
+}
+
+// envelope 将游戏推送的消息包装为带类型的信封
+func envelope(v interface{}) (*Message, error) {
+	switch v := v.(type) {
+	case *Message:
+		return v, nil
+	case *game.StateMessage:
+		return NewMessage(TypeState, v)
+	case *game.DeltaMessage:
+		return NewMessage(TypeDelta, v)
+	case *game.SessionMessage:
+		return NewMessage(TypeWelcome, WelcomePayload{
+			Version: ProtocolVersion,
+			Server:  ServerVersion,
+			Token:   v.Token,
+			SnakeID: v.SnakeID,
+			Name:    v.Name,
+			Color:   v.Color,
+			Config:  v.Config,
+			Map:     v.Map,
+		})
+	case *game.SpectatorMessage:
+		return NewMessage(TypeWelcome, WelcomePayload{
+			Version:     ProtocolVersion,
+			Server:      ServerVersion,
+			SpectatorID: v.ID,
+			Follow:      v.Follow,
+			Config:      v.Config,
+			Map:         v.Map,
+		})
+	case *game.DeathEvent:
+		return NewMessage(TypeDeath, v)
+	case *game.KillEvent:
+		return NewMessage(TypeKill, v)
+	case *game.LeaderboardMessage:
+		return NewMessage(TypeLeaderboard, v)
+	}
+	return nil, fmt.Errorf("未知的消息类型 %T", v)
+}
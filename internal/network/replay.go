@@ -0,0 +1,179 @@
+package network
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"snakesol/internal/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// 回放控制指令
+const (
+	ReplayPlay  = "play"
+	ReplayPause = "pause"
+	ReplaySeek  = "seek"
+	ReplaySpeed = "speed"
+)
+
+// 回放速度的范围
+const (
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 16
+)
+
+// ReplayServer 通过WebSocket向浏览器回放录像，每个连接拥有独立的播放进度
+type ReplayServer struct {
+	rec      *game.Recording
+	config   *Config
+	upgrader websocket.Upgrader
+}
+
+// ReplayInfo 回放进度信息
+type ReplayInfo struct {
+	Tick   uint64  `json:"tick"`
+	Start  uint64  `json:"start"`
+	End    uint64  `json:"end"`
+	Paused bool    `json:"paused"`
+	Speed  float64 `json:"speed"`
+}
+
+// replayState 回放时发送给客户端的游戏状态，地图只在第一条消息中发送
+type replayState struct {
+	*game.StateMessage
+	Replay ReplayInfo    `json:"replay"`
+	Map    *game.GameMap `json:"map,omitempty"`
+}
+
+// replayControl 客户端发送的回放控制指令
+type replayControl struct {
+	Action string  `json:"action"`
+	Tick   uint64  `json:"tick"`
+	Speed  float64 `json:"speed"`
+}
+
+// NewReplayServer 创建一个回放服务器，与游戏的WebSocket接口使用相同的来源检查、消息大小限制和写超时
+func NewReplayServer(config *Config, rec *game.Recording) *ReplayServer {
+	return &ReplayServer{
+		rec:    rec,
+		config: config,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: checkOrigin(config.AllowedOrigins),
+		},
+	}
+}
+
+// HandleConnection 处理观看回放的WebSocket连接
+func (s *ReplayServer) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("升级WebSocket连接失败:", err)
+		return
+	}
+	conn.SetReadLimit(s.config.MaxMessageSize)
+
+	// 回放结束后关闭done，让阻塞在发送指令上的读取goroutine退出，
+	// 关闭连接让阻塞在读取上的goroutine退出
+	controls := make(chan replayControl, 16)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.readControls(conn, controls, done)
+	}()
+
+	s.play(conn, controls)
+	close(done)
+	conn.Close()
+	<-stopped
+}
+
+// readControls 读取客户端的回放控制指令，连接断开或done关闭时关闭controls并返回
+func (s *ReplayServer) readControls(conn game.Connection, controls chan<- replayControl, done <-chan struct{}) {
+	defer close(controls)
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		if msg.Type != TypeReplay {
+			continue
+		}
+		var control replayControl
+		if err := json.Unmarshal(msg.Payload, &control); err != nil {
+			continue
+		}
+		select {
+		case controls <- control:
+		case <-done:
+			return
+		}
+	}
+}
+
+// play 按录像的节奏推进回放并推送状态，直到连接断开
+func (s *ReplayServer) play(conn *websocket.Conn, controls <-chan replayControl) {
+	player := game.NewReplayPlayer(s.rec)
+	interval := time.Duration(s.rec.Header.Config.UpdateInterval) * time.Millisecond
+	info := ReplayInfo{
+		Start: s.rec.StartTick(),
+		End:   s.rec.EndTick(),
+		Speed: 1,
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	gameMap := s.rec.Header.Map
+	send := func() error {
+		info.Tick = player.Tick()
+		msg, err := NewMessage(TypeState, replayState{StateMessage: player.State(), Replay: info, Map: gameMap})
+		if err != nil {
+			return err
+		}
+		gameMap = nil
+		conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+		return conn.WriteJSON(msg)
+	}
+	if err := send(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case control, ok := <-controls:
+			if !ok {
+				return
+			}
+			switch control.Action {
+			case ReplayPlay:
+				// 已播放到末尾时从头开始
+				if player.Tick() >= info.End {
+					player.Seek(info.Start)
+				}
+				info.Paused = false
+			case ReplayPause:
+				info.Paused = true
+			case ReplaySeek:
+				player.Seek(control.Tick)
+			case ReplaySpeed:
+				if control.Speed >= minReplaySpeed && control.Speed <= maxReplaySpeed {
+					info.Speed = control.Speed
+					ticker.Reset(time.Duration(float64(interval) / control.Speed))
+				}
+			}
+		case <-ticker.C:
+			if info.Paused {
+				continue
+			}
+			if !player.Step() {
+				info.Paused = true
+			}
+		}
+		if err := send(); err != nil {
+			return
+		}
+	}
+}
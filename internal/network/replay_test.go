@@ -0,0 +1,146 @@
+package network
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"snakesol/internal/game"
+
+	"github.com/gorilla/websocket"
+)
+
+// bufferCloser 把录像写入内存
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+// testRecording 录制一局只有AI的短游戏
+func testRecording(t *testing.T) *game.Recording {
+	t.Helper()
+	config := game.DefaultConfig()
+	config.Cols, config.Rows = 30, 30
+	config.InitialAICount, config.MaxAICount = 4, 4
+	config.Seed = 1
+	gs, err := game.NewGameState(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bufferCloser{}
+	if err := gs.StartRecording(game.NewRecorder(buf, 10)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		gs.UpdateGame()
+	}
+	if err := gs.StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := game.ReadRecording(&buf.Buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestReplayCheckOrigin(t *testing.T) {
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://snake.example"}
+	server := httptest.NewServer(http.HandlerFunc(NewReplayServer(config, testRecording(t)).HandleConnection))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"https://snake.example", true},
+		{"https://evil.example", false},
+	}
+	for _, tt := range tests {
+		conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {tt.origin}})
+		if tt.ok {
+			if err != nil {
+				t.Fatalf("来源 %s 被拒绝: %v", tt.origin, err)
+			}
+			conn.Close()
+			continue
+		}
+		if err == nil {
+			conn.Close()
+			t.Fatalf("来源 %s 没有被拒绝", tt.origin)
+		}
+		if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("来源 %s 的响应为 %v，期望403", tt.origin, resp)
+		}
+	}
+}
+
+func TestReplayReadLimit(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxMessageSize = 64
+	server := httptest.NewServer(http.HandlerFunc(NewReplayServer(config, testRecording(t)).HandleConnection))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	payload := `{"action":"pause","padding":"` + strings.Repeat("x", 128) + `"}`
+	if err := conn.WriteJSON(Message{Type: TypeReplay, Payload: json.RawMessage(payload)}); err != nil {
+		t.Fatal(err)
+	}
+	// 超过大小限制的消息使服务端关闭连接
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg Message
+		err := conn.ReadJSON(&msg)
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+			t.Fatalf("读取返回 %v，期望服务端以 %d 关闭连接", err, websocket.CloseMessageTooBig)
+		}
+		return
+	}
+}
+
+// controlConn 不断收到暂停指令的连接
+type controlConn struct{}
+
+func (controlConn) WriteJSON(v interface{}) error { return nil }
+
+func (controlConn) ReadJSON(v interface{}) error {
+	msg := v.(*Message)
+	msg.Type, msg.Payload = TypeReplay, json.RawMessage(`{"action":"pause"}`)
+	return nil
+}
+
+func (controlConn) Close() error { return nil }
+
+func TestReadControlsStopsWhenDone(t *testing.T) {
+	// 回放已经结束，没有人接收指令，读取goroutine阻塞在发送上
+	controls := make(chan replayControl, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		(&ReplayServer{}).readControls(controlConn{}, controls, done)
+	}()
+	close(done)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("done关闭后读取goroutine没有退出")
+	}
+	for range controls {
+	}
+}
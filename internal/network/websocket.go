@@ -3,96 +3,245 @@ package network
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"time"
 
 	"snakesol/internal/game"
+	"snakesol/internal/room"
 
 	"github.com/gorilla/websocket"
 )
 
 // WSServer 处理WebSocket连接的服务器
 type WSServer struct {
-	game     *game.GameState
+	config   *Config
+	rooms    *room.Manager
 	upgrader websocket.Upgrader
-}
-
-// Message WebSocket消息结构
-type Message struct {
-	Type    string          `json:"type"`
-	Payload json.RawMessage `json:"payload"`
+	limits   *connLimiter
 }
 
 // NewWSServer 创建一个新的WebSocket服务器
-func NewWSServer(game *game.GameState) *WSServer {
+func NewWSServer(config *Config, rooms *room.Manager) *WSServer {
 	return &WSServer{
-		game: game,
+		config: config,
+		rooms:  rooms,
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(r *http.Request) bool { return true },
+			CheckOrigin: checkOrigin(config.AllowedOrigins),
 		},
+		limits: newConnLimiter(config.MaxConnsPerIP, config.MaxPlayers),
 	}
 }
 
+// ModeSpectate 以观众身份连接，不创建蛇
+const ModeSpectate = "spectate"
+
 // HandleConnection 处理新的WebSocket连接
+// room参数选择要加入的房间，protocol参数选择状态同步协议(full或delta)。
+// 玩家连接建立后需要先发送join消息，服务端回复welcome后才开始推送状态；
+// mode=spectate时以观众身份连接，立即收到welcome和状态，follow参数指定跟随的蛇
+// 同一IP的连接数超过上限时返回429，服务器玩家已满时回复server_full错误后关闭连接，
+// 房间ID不合法或不能再创建房间时回复room_unavailable错误后关闭连接
 func (s *WSServer) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	ip := remoteIP(r)
+	if !s.limits.acquireIP(ip) {
+		http.Error(w, "同一IP的连接数过多", http.StatusTooManyRequests)
+		return
+	}
+
+	// 先完成升级和来源检查再加入房间，普通的HTTP请求不会创建房间
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("升级WebSocket连接失败:", err)
+		s.limits.releaseIP(ip)
+		return
+	}
+
+	query := r.URL.Query()
+	rm, err := s.rooms.Join(query.Get("room"))
+	if err != nil {
+		s.reject(conn, NewError(ErrCodeRoomUnavailable, err.Error()))
+		s.limits.releaseIP(ip)
 		return
 	}
 
-	// 创建新玩家的蛇
-	snake := game.CreateSnake(false)
-	wsConn := &WSConnection{conn: conn}
-	snake.Conn = wsConn
+	protocol := game.ParseProtocol(query.Get("protocol"))
+	if query.Get("mode") == ModeSpectate {
+		// 观众不占用玩家名额
+		wsConn := newWSConnection(conn, s.config)
+		go func() {
+			defer s.limits.releaseIP(ip)
+			s.handleSpectator(rm, wsConn, protocol, query.Get("follow"))
+		}()
+		return
+	}
+
+	if !s.limits.acquirePlayer() {
+		s.reject(conn, NewError(ErrCodeServerFull, "服务器已满，请稍后再试"))
+		s.rooms.Leave(rm)
+		s.limits.releaseIP(ip)
+		return
+	}
 
-	// 将蛇添加到游戏状态
-	s.game.AddSnake(snake)
+	wsConn := newWSConnection(conn, s.config)
+	go func() {
+		defer s.limits.releaseIP(ip)
+		defer s.limits.releasePlayer()
+		s.handlePlayer(rm, wsConn, protocol)
+	}()
+}
 
-	// 处理玩家输入
-	go s.handlePlayerInput(snake)
+// reject 在关闭连接前直接发送一条错误消息，用于还没有发送队列的连接
+func (s *WSServer) reject(conn *websocket.Conn, msg *Message) {
+	deadline := time.Now().Add(s.config.WriteTimeout)
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(msg)
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, ""), deadline)
+	conn.Close()
 }
 
-// handlePlayerInput 处理玩家的输入消息
-func (s *WSServer) handlePlayerInput(snake *game.Snake) {
-	wsConn := snake.Conn.(*WSConnection)
+// handlePlayer 完成加入握手后处理玩家的输入消息
+// 连接出错、超时未响应或消息过大时，立即关闭连接，蛇被冻结等待重连或直接移除
+func (s *WSServer) handlePlayer(rm *room.Room, wsConn *WSConnection, protocol game.Protocol) {
+	defer s.rooms.Leave(rm)
+	defer wsConn.Close()
+
+	snake := s.join(rm, wsConn, protocol)
+	if snake == nil {
+		return
+	}
+	// 复活后玩家控制的蛇会变化，断开时处理最新的蛇
+	defer func() { rm.State.Disconnect(snake.ID, wsConn) }()
 
 	for {
-		var msg Message
-		err := wsConn.ReadJSON(&msg)
+		msg, err := s.readMessage(wsConn)
 		if err != nil {
-			// 从游戏状态中移除蛇，并将其转换为苹果
-			s.game.RemoveSnake(snake.ID)
-			// 关闭WebSocket连接
-			wsConn.Close()
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				log.Printf("玩家 %s 断开连接: %v", snake.ID, err)
+			}
 			return
 		}
 
-		if msg.Type == "direction" {
+		switch msg.Type {
+		case TypeDirection:
 			var dir game.Direction
-			if err := json.Unmarshal(msg.Payload, &dir); err == nil {
-				s.game.UpdateSnakeDirection(snake.ID, dir)
+			if err := json.Unmarshal(msg.Payload, &dir); err != nil {
+				wsConn.WriteJSON(NewError(ErrCodeBadMessage, "无法解析方向: "+err.Error()))
+				continue
+			}
+			if err := rm.State.UpdateSnakeDirection(snake.ID, dir); err != nil {
+				wsConn.WriteJSON(NewError(ErrCodeInvalidInput, err.Error()))
 			}
+		case TypeRespawn:
+			respawned, err := rm.State.RespawnPlayer(snake.ID)
+			if err != nil {
+				wsConn.WriteJSON(NewError(ErrCodeCannotRespawn, err.Error()))
+				continue
+			}
+			snake = respawned
+		case TypeResync:
+			rm.State.Resync(snake.ID)
+		default:
+			wsConn.WriteJSON(NewError(ErrCodeUnknownType, "未知的消息类型: "+msg.Type))
 		}
 	}
 }
 
-// WSConnection 包装websocket.Conn以实现game.Connection接口
-type WSConnection struct {
-	conn *websocket.Conn
-}
+// handleSpectator 处理观众的切换视角和重新同步请求，连接断开后停止推送
+func (s *WSServer) handleSpectator(rm *room.Room, wsConn *WSConnection, protocol game.Protocol, follow string) {
+	defer s.rooms.Leave(rm)
+	defer wsConn.Close()
 
-// WriteJSON 实现game.Connection接口
-func (c *WSConnection) WriteJSON(v interface{}) error {
-	return c.conn.WriteJSON(v)
+	id := rm.State.AddSpectator(wsConn, protocol, follow)
+	defer rm.State.RemoveSpectator(id)
+
+	for {
+		msg, err := s.readMessage(wsConn)
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				log.Printf("观众 %s 断开连接: %v", id, err)
+			}
+			return
+		}
+
+		switch msg.Type {
+		case TypeSpectate:
+			var spectate SpectatePayload
+			if err := json.Unmarshal(msg.Payload, &spectate); err != nil {
+				wsConn.WriteJSON(NewError(ErrCodeBadMessage, "无法解析视角: "+err.Error()))
+				continue
+			}
+			if spectate.Follow != "" {
+				if err := rm.State.Follow(id, spectate.Follow); err != nil {
+					wsConn.WriteJSON(NewError(ErrCodeUnknownSnake, err.Error()))
+				}
+			} else if spectate.Camera != nil {
+				rm.State.MoveCamera(id, *spectate.Camera)
+			}
+		case TypeResync:
+			rm.State.Resync(id)
+		default:
+			wsConn.WriteJSON(NewError(ErrCodeUnknownType, "未知的消息类型: "+msg.Type))
+		}
+	}
 }
 
-// ReadJSON 实现game.Connection接口
-func (c *WSConnection) ReadJSON(v interface{}) error {
-	return c.conn.ReadJSON(v)
+// join 等待客户端的join消息，恢复断线前的蛇或创建新的蛇
+// 加入失败时回复error消息并继续等待，连接断开时返回nil
+func (s *WSServer) join(rm *room.Room, wsConn *WSConnection, protocol game.Protocol) *game.Snake {
+	for {
+		msg, err := s.readMessage(wsConn)
+		if err != nil {
+			return nil
+		}
+		if msg.Type != TypeJoin {
+			wsConn.WriteJSON(NewError(ErrCodeJoinRequired, "请先发送join消息"))
+			continue
+		}
+		var join JoinPayload
+		if len(msg.Payload) > 0 {
+			if err := json.Unmarshal(msg.Payload, &join); err != nil {
+				wsConn.WriteJSON(NewError(ErrCodeBadMessage, "无法解析join消息: "+err.Error()))
+				continue
+			}
+		}
+
+		// 带有效会话令牌的连接接管断线前的蛇
+		if join.Session != "" {
+			if snake := rm.State.ResumePlayer(join.Session, wsConn, protocol); snake != nil {
+				return snake
+			}
+		}
+		snake, _, err := rm.State.AddPlayer(wsConn, protocol, game.PlayerOptions{Name: join.Name, Color: join.Color})
+		if err != nil {
+			wsConn.WriteJSON(NewError(ErrCodeInvalidJoin, err.Error()))
+			continue
+		}
+		return snake
+	}
 }
 
-// Close 实现game.Connection接口
-func (c *WSConnection) Close() error {
-	return c.conn.Close()
+// readMessage 读取一条客户端消息，超过速率限制的消息直接丢弃，无法解析的消息回复error后跳过
+func (s *WSServer) readMessage(wsConn *WSConnection) (*Message, error) {
+	for {
+		var msg Message
+		err := wsConn.ReadJSON(&msg)
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		malformed := errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+		if err != nil && !malformed {
+			return nil, err
+		}
+		if ok, err := wsConn.throttle(); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		if malformed {
+			wsConn.WriteJSON(NewError(ErrCodeBadMessage, "无法解析消息: "+err.Error()))
+			continue
+		}
+		return &msg, nil
+	}
 }
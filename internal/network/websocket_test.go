@@ -0,0 +1,59 @@
+package network
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"snakesol/internal/game"
+	"snakesol/internal/room"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestRoomCreatedOnlyAfterUpgrade(t *testing.T) {
+	gameConfig := game.DefaultConfig()
+	gameConfig.Cols, gameConfig.Rows = 20, 20
+	gameConfig.InitialAICount, gameConfig.MaxAICount = 0, 0
+	rooms, err := room.NewManager(room.DefaultConfig(), gameConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go rooms.Run(ctx)
+	config := DefaultConfig()
+	config.AllowedOrigins = []string{"https://snake.example"}
+	server := httptest.NewServer(http.HandlerFunc(NewWSServer(config, rooms).HandleConnection))
+	defer server.Close()
+	url := server.URL + "/ws?room=probe&mode=spectate"
+
+	// 普通的GET请求和来源不被允许的连接都不会创建房间
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	wsURL := "ws" + strings.TrimPrefix(url, "http")
+	if _, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"https://evil.example"}}); err == nil {
+		t.Fatal("来源不被允许的连接没有被拒绝")
+	}
+	if n := len(rooms.List()); n != 1 {
+		t.Fatalf("升级失败的请求创建了房间，当前有 %d 个房间", n)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"https://snake.example"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var msg Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != TypeWelcome || len(rooms.List()) != 2 {
+		t.Fatalf("收到 %s 消息，当前有 %d 个房间，期望welcome和2个房间", msg.Type, len(rooms.List()))
+	}
+}
@@ -0,0 +1,310 @@
+// Package room 管理多个相互独立的游戏房间
+package room
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"snakesol/internal/game"
+)
+
+var (
+	// ErrInvalidID 房间ID不合法
+	ErrInvalidID = errors.New("房间ID只能包含字母、数字、下划线和连字符，长度1-32")
+	// ErrTooManyRooms 房间数量已达上限
+	ErrTooManyRooms = errors.New("房间数量已达上限")
+	// ErrRoomExists 房间已存在
+	ErrRoomExists = errors.New("房间已存在")
+	// ErrRoomNotFound 房间不存在，并且不允许按需创建
+	ErrRoomNotFound = errors.New("房间不存在")
+)
+
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Config 房间管理配置
+type Config struct {
+	DefaultRoom  string        `yaml:"default_room"`
+	MaxRooms     int           `yaml:"max_rooms"`
+	MaxTempRooms int           `yaml:"max_temp_rooms"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+}
+
+// DefaultConfig 返回默认的房间管理配置
+func DefaultConfig() *Config {
+	return &Config{
+		// 不带room参数的连接加入的房间
+		DefaultRoom: "default",
+		// 同时存在的房间数量上限
+		MaxRooms: 16,
+		// 连接不存在的房间时按需创建的临时房间数量上限，0表示不允许按需创建
+		MaxTempRooms: 4,
+		// 没有任何连接的临时房间在这段时间后被销毁
+		IdleTimeout: time.Minute,
+	}
+}
+
+// Validate 检查配置是否合理
+func (c *Config) Validate() error {
+	switch {
+	case !idPattern.MatchString(c.DefaultRoom):
+		return fmt.Errorf("default_room %q: %w", c.DefaultRoom, ErrInvalidID)
+	case c.MaxRooms <= 0:
+		return fmt.Errorf("max_rooms 必须大于0，当前为 %d", c.MaxRooms)
+	case c.MaxTempRooms < 0:
+		return fmt.Errorf("max_temp_rooms 不能为负数，当前为 %d", c.MaxTempRooms)
+	case c.IdleTimeout < 0:
+		return fmt.Errorf("idle_timeout 不能为负数，当前为 %s", c.IdleTimeout)
+	}
+	return nil
+}
+
+// Room 一个拥有独立游戏状态和游戏循环的房间
+type Room struct {
+	ID    string
+	State *game.GameState
+
+	persistent bool
+	clients    int
+	idleSince  time.Time
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// Info 房间的概要信息，用于大厅列表
+type Info struct {
+	ID         string `json:"id"`
+	Players    int    `json:"players"`
+	AIs        int    `json:"ais"`
+	Clients    int    `json:"clients"`
+	Spectators int    `json:"spectators"`
+	Cols       int    `json:"cols"`
+	Rows       int    `json:"rows"`
+	Persistent bool   `json:"persistent"`
+	// Metrics 房间游戏循环的耗时统计
+	Metrics game.TickMetrics `json:"metrics"`
+}
+
+// Manager 房间管理器
+type Manager struct {
+	config     *Config
+	gameConfig *game.GameConfig
+	results    game.ResultHandler
+
+	mu    sync.Mutex
+	rooms map[string]*Room
+}
+
+// NewManager 创建房间管理器，并创建常驻的默认房间
+// results接收所有房间中玩家的成绩，可以为nil
+func NewManager(config *Config, gameConfig *game.GameConfig, results game.ResultHandler) (*Manager, error) {
+	m := &Manager{
+		config:     config,
+		gameConfig: gameConfig,
+		results:    results,
+		rooms:      make(map[string]*Room),
+	}
+	if _, err := m.Create(config.DefaultRoom, gameConfig, true); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Create 使用指定配置创建一个房间，常驻房间不会因空闲而被销毁
+func (m *Manager) Create(id string, gameConfig *game.GameConfig, persistent bool) (*Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rooms[id]; ok {
+		return nil, ErrRoomExists
+	}
+	return m.create(id, gameConfig, persistent)
+}
+
+// create 创建房间并启动其游戏循环，调用方需持有锁
+func (m *Manager) create(id string, gameConfig *game.GameConfig, persistent bool) (*Room, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrInvalidID
+	}
+	if len(m.rooms) >= m.config.MaxRooms {
+		return nil, ErrTooManyRooms
+	}
+
+	state, err := game.NewGameState(gameConfig)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	room := &Room{
+		ID:         id,
+		State:      state,
+		persistent: persistent,
+		idleSince:  time.Now(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	m.rooms[id] = room
+	room.State.SetResultHandler(m.results)
+	if gameConfig.RecordDir != "" {
+		if err := startRecording(room); err != nil {
+			log.Printf("房间 %s 开启录像失败: %v", id, err)
+		}
+	}
+	go func() {
+		room.State.Run(ctx)
+		close(room.done)
+	}()
+	log.Printf("房间 %s 已创建，随机种子 %d", id, room.State.Seed())
+	return room, nil
+}
+
+// startRecording 在配置的目录中为房间创建录像文件并开始录制
+func startRecording(room *Room) error {
+	config := room.State.Config()
+	if err := os.MkdirAll(config.RecordDir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.snkr", room.ID, time.Now().Format("20060102-150405"))
+	file, err := os.Create(filepath.Join(config.RecordDir, name))
+	if err != nil {
+		return err
+	}
+	if err := room.State.StartRecording(game.NewRecorder(file, config.SnapshotInterval)); err != nil {
+		file.Close()
+		return err
+	}
+	log.Printf("房间 %s 开始录像: %s", room.ID, file.Name())
+	return nil
+}
+
+// Join 为一个新连接获取房间，空ID表示默认房间
+// 房间不存在时使用默认配置创建临时房间，临时房间的数量不超过max_temp_rooms
+// 每次成功的Join都必须对应一次Leave
+func (m *Manager) Join(id string) (*Room, error) {
+	if id == "" {
+		id = m.config.DefaultRoom
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	room, ok := m.rooms[id]
+	if !ok {
+		if m.config.MaxTempRooms == 0 {
+			return nil, ErrRoomNotFound
+		}
+		if m.tempRooms() >= m.config.MaxTempRooms {
+			return nil, ErrTooManyRooms
+		}
+		var err error
+		if room, err = m.create(id, m.gameConfig, false); err != nil {
+			return nil, err
+		}
+	}
+	room.clients++
+	return room, nil
+}
+
+// tempRooms 返回临时房间的数量，调用方需持有锁
+func (m *Manager) tempRooms() int {
+	n := 0
+	for _, room := range m.rooms {
+		if !room.persistent {
+			n++
+		}
+	}
+	return n
+}
+
+// Leave 释放一个连接对房间的占用
+func (m *Manager) Leave(room *Room) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	room.clients--
+	if room.clients == 0 {
+		room.idleSince = time.Now()
+	}
+}
+
+// List 返回所有房间的概要信息，按ID排序
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	rooms := make([]*Room, 0, len(m.rooms))
+	clients := make(map[*Room]int, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+		clients[room] = room.clients
+	}
+	m.mu.Unlock()
+
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].ID < rooms[j].ID })
+	infos := make([]Info, 0, len(rooms))
+	for _, room := range rooms {
+		config := room.State.Config()
+		infos = append(infos, Info{
+			ID:         room.ID,
+			Players:    room.State.PlayerCount(),
+			AIs:        room.State.AICount(),
+			Clients:    clients[room],
+			Spectators: room.State.SpectatorCount(),
+			Cols:       config.Cols,
+			Rows:       config.Rows,
+			Persistent: room.persistent,
+			Metrics:    room.State.Metrics(),
+		})
+	}
+	return infos
+}
+
+// Run 定期销毁空闲的临时房间，直到ctx被取消
+func (m *Manager) Run(ctx context.Context) {
+	interval := m.config.IdleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			m.closeAll()
+			return
+		case <-ticker.C:
+			m.reap(time.Now())
+		}
+	}
+}
+
+// reap 销毁没有连接且空闲超时的临时房间
+func (m *Manager) reap(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, room := range m.rooms {
+		if room.persistent || room.clients > 0 {
+			continue
+		}
+		if now.Sub(room.idleSince) >= m.config.IdleTimeout {
+			room.cancel()
+			delete(m.rooms, id)
+			log.Printf("房间 %s 空闲已销毁", id)
+		}
+	}
+}
+
+// closeAll 停止所有房间的游戏循环，并等待它们关闭录像
+func (m *Manager) closeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, room := range m.rooms {
+		room.cancel()
+	}
+	for id, room := range m.rooms {
+		<-room.done
+		delete(m.rooms, id)
+	}
+}
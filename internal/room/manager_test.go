@@ -0,0 +1,143 @@
+package room
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"snakesol/internal/game"
+)
+
+// newTestManager 创建使用小场地、没有AI蛇的房间管理器，测试结束时关闭所有房间
+func newTestManager(t *testing.T, config *Config) *Manager {
+	t.Helper()
+	gameConfig := game.DefaultConfig()
+	gameConfig.Cols, gameConfig.Rows = 20, 20
+	gameConfig.InitialAICount, gameConfig.MaxAICount = 0, 0
+	m, err := NewManager(config, gameConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.closeAll)
+	return m
+}
+
+func TestJoinCreatesRoom(t *testing.T) {
+	m := newTestManager(t, DefaultConfig())
+
+	def, err := m.Join("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.ID != "default" || !def.persistent {
+		t.Fatalf("空ID加入了房间 %s(常驻: %v)，期望常驻的默认房间", def.ID, def.persistent)
+	}
+
+	room, err := m.Join("lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.persistent || room.clients != 1 {
+		t.Fatalf("新房间常驻: %v、连接数 %d，期望临时房间、1", room.persistent, room.clients)
+	}
+	again, err := m.Join("lobby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != room || room.clients != 2 {
+		t.Fatalf("再次加入得到了不同的房间或连接数为 %d", room.clients)
+	}
+	if len(m.List()) != 2 {
+		t.Fatalf("房间列表有 %d 个房间，期望 2", len(m.List()))
+	}
+
+	if _, err := m.Join("bad id!"); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("Join(bad id!) = %v，期望 %v", err, ErrInvalidID)
+	}
+}
+
+func TestLeaveSetsIdleTime(t *testing.T) {
+	m := newTestManager(t, DefaultConfig())
+	room, _ := m.Join("lobby")
+	m.Join("lobby")
+	created := room.idleSince
+
+	time.Sleep(10 * time.Millisecond)
+	m.Leave(room)
+	if room.clients != 1 || room.idleSince != created {
+		t.Fatalf("还有连接时连接数为 %d、空闲时间被更新", room.clients)
+	}
+	m.Leave(room)
+	if room.clients != 0 || !room.idleSince.After(created) {
+		t.Fatalf("最后一个连接离开后连接数为 %d、空闲时间没有更新", room.clients)
+	}
+}
+
+func TestReap(t *testing.T) {
+	config := DefaultConfig()
+	config.IdleTimeout = time.Minute
+	m := newTestManager(t, config)
+	idle, _ := m.Join("idle")
+	m.Leave(idle)
+	busy, _ := m.Join("busy")
+
+	// 空闲时间不足时不销毁
+	m.reap(idle.idleSince.Add(time.Second))
+	if _, ok := m.rooms["idle"]; !ok {
+		t.Fatal("空闲时间不足的房间被销毁")
+	}
+
+	m.reap(idle.idleSince.Add(time.Hour))
+	if _, ok := m.rooms["idle"]; ok {
+		t.Fatal("空闲超时的临时房间没有被销毁")
+	}
+	select {
+	case <-idle.done:
+	case <-time.After(time.Second):
+		t.Fatal("被销毁的房间的游戏循环没有停止")
+	}
+	if _, ok := m.rooms["busy"]; !ok {
+		t.Fatal("还有连接的房间被销毁")
+	}
+	if _, ok := m.rooms["default"]; !ok {
+		t.Fatal("常驻房间被销毁")
+	}
+	m.Leave(busy)
+}
+
+func TestRoomLimits(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxRooms  int
+		maxTemp   int
+		created   int // 能够按需创建的临时房间数
+		wantErr   error
+		createErr error // 达到上限后Create常驻房间的结果
+	}{
+		{"临时房间达到上限", 16, 2, 2, ErrTooManyRooms, nil},
+		{"房间总数达到上限", 3, 4, 2, ErrTooManyRooms, ErrTooManyRooms},
+		{"不允许按需创建", 16, 0, 0, ErrRoomNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.MaxRooms, config.MaxTempRooms = tt.maxRooms, tt.maxTemp
+			m := newTestManager(t, config)
+			for i := 0; i < tt.created; i++ {
+				if _, err := m.Join(string(rune('a' + i))); err != nil {
+					t.Fatalf("第 %d 个临时房间: %v", i+1, err)
+				}
+			}
+			if _, err := m.Join("extra"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Join(extra) = %v，期望 %v", err, tt.wantErr)
+			}
+			if _, err := m.Create("fixed", m.gameConfig, true); !errors.Is(err, tt.createErr) {
+				t.Fatalf("Create(fixed) = %v，期望 %v", err, tt.createErr)
+			}
+			// 已存在的房间不受上限影响
+			if _, err := m.Join(""); err != nil {
+				t.Fatalf("加入默认房间: %v", err)
+			}
+		})
+	}
+}
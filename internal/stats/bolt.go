@@ -0,0 +1,92 @@
+package stats
+
+import (
+	"encoding/json"
+	"time"
+
+	"snakesol/internal/game"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// playersBucket 以昵称为键、JSON编码的PlayerStats为值
+var playersBucket = []byte("players")
+
+// BoltStore 保存在BoltDB文件中的统计，服务器重启后保留
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore 打开或创建数据文件
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(playersBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Record 实现Store接口
+func (s *BoltStore) Record(result game.PlayerResult) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(playersBucket)
+		stats := &PlayerStats{}
+		if data := bucket.Get([]byte(result.Name)); data != nil {
+			if err := json.Unmarshal(data, stats); err != nil {
+				return err
+			}
+		}
+		stats.add(result, time.Now())
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(result.Name), data)
+	})
+}
+
+// Get 实现Store接口
+func (s *BoltStore) Get(name string) (*PlayerStats, error) {
+	var stats *PlayerStats
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(playersBucket).Get([]byte(name))
+		if data == nil {
+			return ErrNotFound
+		}
+		stats = &PlayerStats{}
+		return json.Unmarshal(data, stats)
+	})
+	return stats, err
+}
+
+// Top 实现Store接口，遍历所有玩家后排序，适合玩家数量不大的服务器
+func (s *BoltStore) Top(n int) ([]*PlayerStats, error) {
+	all := make([]*PlayerStats, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(playersBucket).ForEach(func(k, v []byte) error {
+			stats := &PlayerStats{}
+			if err := json.Unmarshal(v, stats); err != nil {
+				return err
+			}
+			all = append(all, stats)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortTop(all, n), nil
+}
+
+// Close 实现Store接口
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
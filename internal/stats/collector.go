@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"log"
+	"sync"
+
+	"snakesol/internal/game"
+)
+
+// collectorQueueSize 等待写入存储的成绩数量上限
+const collectorQueueSize = 256
+
+// Collector 在独立的goroutine中把游戏上报的成绩写入存储，避免游戏循环等待磁盘
+type Collector struct {
+	store   Store
+	results chan game.PlayerResult
+	done    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewCollector 创建并启动写入goroutine
+func NewCollector(store Store) *Collector {
+	c := &Collector{
+		store:   store,
+		results: make(chan game.PlayerResult, collectorQueueSize),
+		done:    make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Add 实现game.ResultHandler，队列已满或已经关闭时丢弃成绩而不阻塞游戏循环
+// 服务器退出时连接可能在Close之后才断开，因此Close之后仍然可以调用
+func (c *Collector) Add(result game.PlayerResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		log.Printf("统计已关闭，丢弃玩家 %s 的成绩", result.Name)
+		return
+	}
+	select {
+	case c.results <- result:
+	default:
+		log.Printf("统计队列已满，丢弃玩家 %s 的成绩", result.Name)
+	}
+}
+
+func (c *Collector) run() {
+	defer close(c.done)
+	for result := range c.results {
+		if err := c.store.Record(result); err != nil {
+			log.Printf("保存玩家 %s 的统计失败: %v", result.Name, err)
+		}
+	}
+}
+
+// Close 写完队列中剩余的成绩后返回，之后Add的成绩被丢弃，重复调用时直接返回
+func (c *Collector) Close() {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.results)
+	}
+	c.mu.Unlock()
+	<-c.done
+}
@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"testing"
+
+	"snakesol/internal/game"
+)
+
+func TestCollectorFlushesOnClose(t *testing.T) {
+	store := NewMemoryStore()
+	c := NewCollector(store)
+	for i := 0; i < 3; i++ {
+		c.Add(game.PlayerResult{Name: "Bob", Score: i})
+	}
+	c.Close()
+
+	stats, err := store.Get("Bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Games != 3 {
+		t.Fatalf("Games = %d，期望 3", stats.Games)
+	}
+}
+
+func TestCollectorAddAfterClose(t *testing.T) {
+	store := NewMemoryStore()
+	c := NewCollector(store)
+	c.Close()
+	c.Add(game.PlayerResult{Name: "Bob"})
+	c.Close()
+
+	if _, err := store.Get("Bob"); err != ErrNotFound {
+		t.Fatalf("关闭后添加的成绩被保存: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"snakesol/internal/game"
+)
+
+// MemoryStore 保存在内存中的统计，用于测试和没有配置数据文件的服务器
+type MemoryStore struct {
+	mu      sync.Mutex
+	players map[string]*PlayerStats
+}
+
+// NewMemoryStore 创建空的内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{players: make(map[string]*PlayerStats)}
+}
+
+// Record 实现Store接口
+func (s *MemoryStore) Record(result game.PlayerResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.players[result.Name]
+	if !ok {
+		stats = &PlayerStats{}
+		s.players[result.Name] = stats
+	}
+	stats.add(result, time.Now())
+	return nil
+}
+
+// Get 实现Store接口
+func (s *MemoryStore) Get(name string) (*PlayerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.players[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *stats
+	return &copied, nil
+}
+
+// Top 实现Store接口
+func (s *MemoryStore) Top(n int) ([]*PlayerStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	all := make([]*PlayerStats, 0, len(s.players))
+	for _, stats := range s.players {
+		copied := *stats
+		all = append(all, &copied)
+	}
+	return sortTop(all, n), nil
+}
+
+// Close 实现Store接口
+func (s *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,107 @@
+// Package stats 保存玩家跨局的历史统计和历史最高分
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"snakesol/internal/game"
+)
+
+// ErrNotFound 没有该昵称的统计
+var ErrNotFound = errors.New("没有该玩家的统计")
+
+// Config 统计存储配置
+type Config struct {
+	Path     string `yaml:"path"`
+	TopLimit int    `yaml:"top_limit"`
+}
+
+// DefaultConfig 返回默认的统计存储配置
+func DefaultConfig() *Config {
+	return &Config{
+		// BoltDB数据文件路径，为空时只保存在内存中，重启后丢失
+		Path: "",
+		// 历史排行榜一次最多返回的玩家数
+		TopLimit: 100,
+	}
+}
+
+// Validate 检查配置是否合理
+func (c *Config) Validate() error {
+	switch {
+	case c.TopLimit <= 0:
+		return fmt.Errorf("top_limit 必须大于0，当前为 %d", c.TopLimit)
+	}
+	return nil
+}
+
+// PlayerStats 一个昵称的历史统计
+type PlayerStats struct {
+	Name            string    `json:"name"`
+	Games           int       `json:"games"`           // 玩过的局数，每条蛇死亡或离开算一局
+	Kills           int       `json:"kills"`           // 累计击杀数
+	Apples          int       `json:"apples"`          // 累计吃到的苹果数
+	BestScore       int       `json:"bestScore"`       // 单局最高分
+	BestLength      int       `json:"bestLength"`      // 单局达到过的最大长度
+	LongestSurvival float64   `json:"longestSurvival"` // 单局最长存活时间(秒)
+	LastPlayed      time.Time `json:"lastPlayed"`
+}
+
+// add 将一局的成绩累加到统计中
+func (s *PlayerStats) add(result game.PlayerResult, now time.Time) {
+	s.Name = result.Name
+	s.Games++
+	s.Kills += result.Kills
+	s.Apples += result.Apples
+	if result.Score > s.BestScore {
+		s.BestScore = result.Score
+	}
+	if result.MaxLength > s.BestLength {
+		s.BestLength = result.MaxLength
+	}
+	if result.Alive > s.LongestSurvival {
+		s.LongestSurvival = result.Alive
+	}
+	s.LastPlayed = now
+}
+
+// Store 玩家统计的存储
+type Store interface {
+	// Record 将一局的成绩累加到该昵称的统计中
+	Record(result game.PlayerResult) error
+	// Get 返回昵称的统计，没有时返回ErrNotFound
+	Get(name string) (*PlayerStats, error)
+	// Top 返回单局最高分最高的n个昵称
+	Top(n int) ([]*PlayerStats, error)
+	// Close 关闭存储
+	Close() error
+}
+
+// Open 按配置打开统计存储，没有配置路径时使用内存存储
+func Open(config *Config) (Store, error) {
+	if config.Path == "" {
+		return NewMemoryStore(), nil
+	}
+	return OpenBoltStore(config.Path)
+}
+
+// sortTop 按单局最高分排序并截取前n个，分数相同时按最大长度、昵称排序
+func sortTop(all []*PlayerStats, n int) []*PlayerStats {
+	sort.Slice(all, func(i, j int) bool {
+		a, b := all[i], all[j]
+		if a.BestScore != b.BestScore {
+			return a.BestScore > b.BestScore
+		}
+		if a.BestLength != b.BestLength {
+			return a.BestLength > b.BestLength
+		}
+		return a.Name < b.Name
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
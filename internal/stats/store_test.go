@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"snakesol/internal/game"
+)
+
+// stores 返回要测试的各种存储
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	bolt, err := OpenBoltStore(filepath.Join(t.TempDir(), "stats.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bolt.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "bolt": bolt}
+}
+
+func TestStoreRecord(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			results := []game.PlayerResult{
+				{Name: "Bob", Score: 12, Kills: 1, Apples: 2, MaxLength: 8, Alive: 30},
+				{Name: "Bob", Score: 5, Kills: 0, Apples: 5, MaxLength: 11, Alive: 12.5},
+				{Name: "Alice", Score: 3, Apples: 3, MaxLength: 4, Alive: 5},
+			}
+			for _, result := range results {
+				if err := store.Record(result); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stats, err := store.Get("Bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.LastPlayed.IsZero() {
+				t.Error("LastPlayed 没有记录")
+			}
+			want := PlayerStats{Name: "Bob", Games: 2, Kills: 1, Apples: 7, BestScore: 12, BestLength: 11, LongestSurvival: 30, LastPlayed: stats.LastPlayed}
+			if !reflect.DeepEqual(*stats, want) {
+				t.Fatalf("Get(Bob) = %+v，期望 %+v", *stats, want)
+			}
+
+			if _, err := store.Get("Carol"); err != ErrNotFound {
+				t.Fatalf("Get(Carol) 返回 %v，期望 %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestStoreGetReturnsCopy(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Record(game.PlayerResult{Name: "Bob", Score: 10}); err != nil {
+				t.Fatal(err)
+			}
+			stats, _ := store.Get("Bob")
+			stats.BestScore = 100
+			top, _ := store.Top(1)
+			top[0].Games = 100
+			if stats, _ := store.Get("Bob"); stats.BestScore != 10 || stats.Games != 1 {
+				t.Fatalf("修改返回值影响了存储: %+v", *stats)
+			}
+		})
+	}
+}
+
+func TestStoreTop(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			results := []game.PlayerResult{
+				{Name: "Dave", Score: 5, MaxLength: 9},
+				{Name: "Bob", Score: 20, MaxLength: 4},
+				{Name: "Carol", Score: 5, MaxLength: 9},
+				{Name: "Alice", Score: 5, MaxLength: 12},
+				{Name: "Eve", Score: 1, MaxLength: 3},
+				// 较低的分数不影响最高分
+				{Name: "Eve", Score: 0, MaxLength: 2},
+			}
+			for _, result := range results {
+				if err := store.Record(result); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tests := []struct {
+				n    int
+				want []string
+			}{
+				{3, []string{"Bob", "Alice", "Carol"}},
+				{10, []string{"Bob", "Alice", "Carol", "Dave", "Eve"}},
+				{0, []string{}},
+			}
+			for _, tt := range tests {
+				top, err := store.Top(tt.n)
+				if err != nil {
+					t.Fatal(err)
+				}
+				names := make([]string, 0, len(top))
+				for _, stats := range top {
+					names = append(names, stats.Name)
+				}
+				if !reflect.DeepEqual(names, tt.want) {
+					t.Errorf("Top(%d) = %v，期望 %v", tt.n, names, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"errors"
 	"flag"
 	"log"
-	"math/rand"
-	"time"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"snakesol/internal/config"
 	"snakesol/internal/game"
 	"snakesol/internal/http"
+	"snakesol/internal/network"
+	"snakesol/internal/room"
+	"snakesol/internal/stats"
 )
 
 //go:generate go run github.com/markbates/pkger/cmd/pkger -o server
@@ -17,30 +24,92 @@ import (
 var staticFiles embed.FS
 
 func main() {
-	// 解析命令行参数
-	port := flag.String("port", "8080", "服务器监听端口")
-	flag.Parse()
+	// 子命令 replay <file> 回放一段录像
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
+	// 加载配置：默认值 < 配置文件 < 环境变量 < 命令行参数
+	cfg, err := config.Load(os.Args[0], os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("加载配置失败:", err)
+	}
+
+	// 收到退出信号时停止所有房间，保证录像完整写入
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 初始化随机数种子
-	rand.Seed(time.Now().UnixNano())
+	// 打开玩家统计存储，所有房间的玩家成绩在后台写入
+	store, err := stats.Open(cfg.Stats)
+	if err != nil {
+		log.Fatal("打开统计存储失败:", err)
+	}
+	collector := stats.NewCollector(store)
 
-	// 创建游戏状态
-	gameState := game.NewGameState()
+	// 创建房间管理器，默认房间和配置文件中定义的房间常驻
+	rooms, err := room.NewManager(cfg.Room, cfg.Game, collector.Add)
+	if err != nil {
+		log.Fatalf("创建默认房间 %s 失败: %v", cfg.Room.DefaultRoom, err)
+	}
+	for _, spec := range cfg.Rooms {
+		if _, err := rooms.Create(spec.ID, spec.Game, true); err != nil {
+			log.Fatalf("创建房间 %s 失败: %v", spec.ID, err)
+		}
+	}
+	roomsDone := make(chan struct{})
+	go func() {
+		rooms.Run(ctx)
+		close(roomsDone)
+	}()
 
-	// 启动游戏循环
+	// 创建并启动HTTP服务器
+	server := http.NewServer(cfg.HTTP, rooms, network.NewWSServer(cfg.WS, rooms), store, cfg.Stats.TopLimit, staticFiles)
 	go func() {
-		ticker := time.NewTicker(time.Duration(game.DefaultConfig().UpdateInterval) * time.Millisecond)
-		for range ticker.C {
-			gameState.UpdateGame()
+		if err := server.Start(); err != nil {
+			log.Fatal("服务器启动失败:", err)
 		}
 	}()
 
-	// 创建HTTP服务器配置
-	config := http.DefaultConfig()
-	config.Addr = ":" + *port
+	<-ctx.Done()
+	<-roomsDone
+	collector.Close()
+	if err := store.Close(); err != nil {
+		log.Println("关闭统计存储失败:", err)
+	}
+	log.Println("服务器已停止")
+}
 
-	// 创建并启动HTTP服务器
-	server := http.NewServer(config, gameState, staticFiles)
+// runReplay 加载录像文件并通过/ws提供回放
+func runReplay(args []string) {
+	if len(args) == 0 || args[0] == "" || args[0][0] == '-' {
+		log.Fatal("用法: snakesol replay <录像文件> [-port 端口] [其他参数]")
+	}
+	path := args[0]
+
+	cfg, err := config.Load("replay", args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal("加载配置失败:", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal("打开录像失败:", err)
+	}
+	rec, err := game.ReadRecording(file)
+	file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("已加载录像 %s，tick %d-%d，随机种子 %d", path, rec.StartTick(), rec.EndTick(), rec.Header.Seed)
+
+	server := http.NewReplayServer(cfg.HTTP, network.NewReplayServer(cfg.WS, rec), staticFiles)
 	if err := server.Start(); err != nil {
 		log.Fatal("服务器启动失败:", err)
 	}